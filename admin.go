@@ -0,0 +1,188 @@
+package opentelemetry
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/firebase/genkit/go/genkit"
+)
+
+// defaultAdminAddr only accepts connections from the local host.
+const defaultAdminAddr = "localhost:9091"
+
+// adminTokenEnv is read when Config.AdminToken is empty.
+const adminTokenEnv = "GENKIT_OTEL_ADMIN_TOKEN"
+
+// errUnknownFlow is returned by EnableDebug for names that are not flows.
+var errUnknownFlow = errors.New("unknown flow")
+
+// RegisterFlows makes the flows of g available to debug overrides. Call it
+// after genkit.Init; flows defined later are found too.
+func (ot *OpenTelemetry) RegisterFlows(g *genkit.Genkit) {
+	ot.debug.registry.Store(g)
+}
+
+// EnableDebug samples every run of flow and marks its traces with
+// genkit.debug=true until ttl has passed, replacing any earlier override
+// for the flow. A ttl of 0 uses the default of 15 minutes. The flow must be
+// registered with the Genkit instance passed to RegisterFlows.
+func (ot *OpenTelemetry) EnableDebug(flow string, ttl time.Duration) (DebugOverride, error) {
+	if flow == "" {
+		return DebugOverride{}, fmt.Errorf("flow name is required")
+	}
+	if ot.debug.registry.Load() == nil {
+		return DebugOverride{}, fmt.Errorf("debug overrides need RegisterFlows to be called first")
+	}
+	if !ot.debug.isFlow(flow) {
+		return DebugOverride{}, fmt.Errorf("%w %q", errUnknownFlow, flow)
+	}
+	if ttl == 0 {
+		ttl = defaultDebugOverrideTTL
+	}
+	if ttl < 0 || ttl > maxDebugOverrideTTL {
+		return DebugOverride{}, fmt.Errorf("debug override TTL %v must be between 0 and %v", ttl, maxDebugOverrideTTL)
+	}
+
+	override := ot.debug.enable(flow, ttl)
+	slog.Info("Enabled debug telemetry", "flow", flow, "expires_at", override.ExpiresAt)
+	return override, nil
+}
+
+// DisableDebug removes the debug override for flow and reports whether one
+// was active.
+func (ot *OpenTelemetry) DisableDebug(flow string) bool {
+	active := ot.debug.disable(flow)
+	if active {
+		slog.Info("Disabled debug telemetry", "flow", flow)
+	}
+	return active
+}
+
+// DebugOverrides returns the active debug overrides sorted by flow.
+func (ot *OpenTelemetry) DebugOverrides() []DebugOverride {
+	return ot.debug.list()
+}
+
+// Debugging reports whether flow has an active debug override, for
+// decisions that should capture everything while a flow is debugged.
+func (ot *OpenTelemetry) Debugging(flow string) bool {
+	return ot.debug.active(flow)
+}
+
+// AdminHandler returns an http.Handler managing debug overrides:
+//
+//	GET    /debug/flows               list active overrides
+//	PUT    /debug/flows/{flow}?ttl=1h enable an override, 15m by default
+//	DELETE /debug/flows/{flow}        disable an override
+//
+// Requests must carry the token as "Authorization: Bearer <token>". An empty
+// token rejects every request.
+func (ot *OpenTelemetry) AdminHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /debug/flows", func(w http.ResponseWriter, _ *http.Request) {
+		writeAdminJSON(w, http.StatusOK, ot.DebugOverrides())
+	})
+	mux.HandleFunc("PUT /debug/flows/{flow}", func(w http.ResponseWriter, r *http.Request) {
+		var ttl time.Duration
+		if value := r.URL.Query().Get("ttl"); value != "" {
+			var err error
+			if ttl, err = time.ParseDuration(value); err != nil {
+				writeAdminError(w, http.StatusBadRequest, fmt.Errorf("invalid ttl: %w", err))
+				return
+			}
+		}
+		override, err := ot.EnableDebug(r.PathValue("flow"), ttl)
+		if errors.Is(err, errUnknownFlow) {
+			writeAdminError(w, http.StatusNotFound, err)
+			return
+		} else if err != nil {
+			writeAdminError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, override)
+	})
+	mux.HandleFunc("DELETE /debug/flows/{flow}", func(w http.ResponseWriter, r *http.Request) {
+		if !ot.DisableDebug(r.PathValue("flow")) {
+			writeAdminError(w, http.StatusNotFound, fmt.Errorf("no debug override for flow %q", r.PathValue("flow")))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeAdminError(w, http.StatusUnauthorized, fmt.Errorf("unauthorized"))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// writeAdminJSON writes v as the JSON response body.
+func writeAdminJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeAdminError writes err as a JSON error response.
+func writeAdminError(w http.ResponseWriter, status int, err error) {
+	writeAdminJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// startAdminServer serves AdminHandler on Config.AdminAddr.
+func (ot *OpenTelemetry) startAdminServer() error {
+	token := ot.config.AdminToken
+	if token == "" {
+		token = os.Getenv(adminTokenEnv)
+	}
+	if token == "" {
+		return fmt.Errorf("admin endpoint requires Config.AdminToken or %s", adminTokenEnv)
+	}
+
+	listener, err := net.Listen("tcp", ot.config.AdminAddr)
+	if err != nil {
+		return fmt.Errorf("failed to start admin server on %s: %w", ot.config.AdminAddr, err)
+	}
+	ot.adminServer = &http.Server{
+		Handler:           ot.AdminHandler(token),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ot.serverWg.Add(1)
+	go func() {
+		defer ot.serverWg.Done()
+		if err := ot.adminServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			slog.Error("Admin server failed", "error", err)
+		}
+	}()
+	slog.Info("Admin server started", "addr", listener.Addr().String())
+	return nil
+}
+
+// shutdownAdminServer stops the admin server if it was started.
+func (ot *OpenTelemetry) shutdownAdminServer(ctx context.Context) error {
+	if ot.adminServer == nil {
+		return nil
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := ot.adminServer.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Error shutting down admin server", "error", err)
+		return fmt.Errorf("failed to shutdown admin server: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package opentelemetry_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/firebase/genkit/go/genkit"
+	opentelemetry "github.com/xavidop/genkit-opentelemetry-go"
+	"github.com/xavidop/genkit-opentelemetry-go/testutil"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// adminRequest sends a request to an admin handler and returns the response
+// status and body.
+func adminRequest(t *testing.T, handler http.Handler, method, target, authorization string) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest(method, target, nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code, rec.Body.String()
+}
+
+func TestAdminHandlerAuthentication(t *testing.T) {
+	tel, _ := newTestGenkit(t)
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		want          int
+	}{
+		{"valid token", "s3cret", "Bearer s3cret", http.StatusOK},
+		{"no header", "s3cret", "", http.StatusUnauthorized},
+		{"wrong token", "s3cret", "Bearer wrong", http.StatusUnauthorized},
+		{"token prefix", "s3cret", "Bearer s3c", http.StatusUnauthorized},
+		{"other scheme", "s3cret", "Basic s3cret", http.StatusUnauthorized},
+		{"empty token", "", "Bearer ", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := tel.Plugin.AdminHandler(tt.token)
+			status, _ := adminRequest(t, handler, http.MethodGet, "/debug/flows", tt.authorization)
+			if status != tt.want {
+				t.Errorf("status = %d, want %d", status, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdminHandlerDebugOverrides(t *testing.T) {
+	tel, g := newTestGenkit(t, opentelemetry.Config{Sampler: sdktrace.ParentBased(sdktrace.NeverSample())})
+	flow := defineGenerateFlow(g, "adminFlow")
+	handler := tel.Plugin.AdminHandler("s3cret")
+	const auth = "Bearer s3cret"
+
+	if status, body := adminRequest(t, handler, http.MethodPut, "/debug/flows/adminFlow", auth); status != http.StatusBadRequest {
+		t.Errorf("PUT before RegisterFlows = %d %s, want %d", status, body, http.StatusBadRequest)
+	}
+	tel.Plugin.RegisterFlows(g)
+
+	errorTests := []struct {
+		target string
+		want   int
+	}{
+		{"/debug/flows/unknownFlow", http.StatusNotFound},
+		{"/debug/flows/test%2Fmodel", http.StatusNotFound}, // a model, not a flow
+		{"/debug/flows/adminFlow?ttl=soon", http.StatusBadRequest},
+		{"/debug/flows/adminFlow?ttl=48h", http.StatusBadRequest},
+		{"/debug/flows/adminFlow?ttl=-1m", http.StatusBadRequest},
+	}
+	for _, tt := range errorTests {
+		if status, body := adminRequest(t, handler, http.MethodPut, tt.target, auth); status != tt.want {
+			t.Errorf("PUT %s = %d %s, want %d", tt.target, status, body, tt.want)
+		}
+	}
+
+	// Runs of the flow are not sampled until it is debugged
+	runFlow(t, flow, "hi")
+	tel.Flush()
+	if spans := tel.Spans.GetSpans(); len(spans) > 0 {
+		t.Fatalf("%d spans exported without a debug override", len(spans))
+	}
+
+	status, body := adminRequest(t, handler, http.MethodPut, "/debug/flows/adminFlow?ttl=1h", auth)
+	if status != http.StatusOK {
+		t.Fatalf("PUT = %d %s, want %d", status, body, http.StatusOK)
+	}
+	var override opentelemetry.DebugOverride
+	if err := json.Unmarshal([]byte(body), &override); err != nil {
+		t.Fatalf("PUT response is not a debug override: %v", err)
+	}
+	if override.Flow != "adminFlow" || time.Until(override.ExpiresAt) <= 59*time.Minute {
+		t.Errorf("PUT = %+v, want adminFlow expiring in an hour", override)
+	}
+
+	status, body = adminRequest(t, handler, http.MethodGet, "/debug/flows", auth)
+	var overrides []opentelemetry.DebugOverride
+	if err := json.Unmarshal([]byte(body), &overrides); status != http.StatusOK || err != nil {
+		t.Fatalf("GET = %d %s", status, body)
+	}
+	if len(overrides) != 1 || overrides[0].Flow != "adminFlow" {
+		t.Errorf("GET = %+v, want the adminFlow override", overrides)
+	}
+
+	runFlow(t, flow, "hi")
+	tel.AssertSpan("adminFlow", attribute.Bool("genkit.debug", true))
+	tel.AssertSpan(testModel)
+
+	if status, body := adminRequest(t, handler, http.MethodDelete, "/debug/flows/adminFlow", auth); status != http.StatusNoContent {
+		t.Errorf("DELETE = %d %s, want %d", status, body, http.StatusNoContent)
+	}
+	if status, _ := adminRequest(t, handler, http.MethodDelete, "/debug/flows/adminFlow", auth); status != http.StatusNotFound {
+		t.Errorf("second DELETE = %d, want %d", status, http.StatusNotFound)
+	}
+
+	tel.Reset()
+	runFlow(t, flow, "hi")
+	tel.Flush()
+	if spans := tel.Spans.GetSpans(); len(spans) > 0 {
+		t.Errorf("%d spans exported after the override was removed", len(spans))
+	}
+}
+
+func TestAdminEndpointRequiresToken(t *testing.T) {
+	t.Setenv("GENKIT_OTEL_ADMIN_TOKEN", "")
+	tel := testutil.NewForTest(t, opentelemetry.Config{EnableAdminEndpoint: true, AdminAddr: "127.0.0.1:0"})
+
+	defer func() {
+		if recover() == nil {
+			t.Error("genkit.Init succeeded without an admin token")
+		}
+	}()
+	genkit.Init(context.Background(), genkit.WithPlugins(tel.Plugin))
+}
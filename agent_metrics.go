@@ -0,0 +1,247 @@
+package opentelemetry
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// Span attributes linking a tool call to the model generation that requested it.
+const (
+	toolModelAttr       = "genkit.tool.model"
+	toolModelSpanIDAttr = "genkit.tool.model_span_id"
+	generateTurnAttr    = "genkit.generate.turn"
+)
+
+// agentTelemetry is a span processor that follows Genkit tool-calling loops.
+// It emits per-tool call and latency metrics, the number of turns and tool
+// calls of each generate loop, and links tool spans to the model span whose
+// response requested them.
+//
+// Each turn of a loop is a "generate" util span nested in the previous one,
+// with the model call and the requested tool calls as its children.
+type agentTelemetry struct {
+	toolRequests otelmetric.Int64Counter
+	toolLatency  otelmetric.Float64Histogram
+	turns        otelmetric.Int64Histogram
+	toolCalls    otelmetric.Int64Histogram
+
+	// Span attributes, such as promoted baggage keys, copied onto the tool
+	// and loop metrics
+	dimensionKeys []string
+
+	// Model span outputs, read for the tool requests of each turn
+	payloads *spanPayloads
+
+	mu    sync.Mutex
+	loops map[oteltrace.SpanID]*generateTurn
+}
+
+// generateTurn is the state of an in-flight generate span.
+type generateTurn struct {
+	parent oteltrace.SpanID
+	turn   int // 1-based position in the loop
+
+	// Turns and tool calls of this span and the ones nested in it
+	turns     int
+	toolCalls int
+
+	// The model call of this turn and the tools its response requested
+	model        oteltrace.SpanContext
+	modelName    string
+	toolRequests map[string]bool
+}
+
+// newAgentTelemetry creates the tool and loop instruments on the given meter
+// provider. dimensionKeys are span attributes copied onto every data point.
+func newAgentTelemetry(mp otelmetric.MeterProvider, dimensionKeys []string, payloads *spanPayloads) (*agentTelemetry, error) {
+	meter := mp.Meter(genkitMeterName)
+	a := &agentTelemetry{
+		dimensionKeys: dimensionKeys,
+		payloads:      payloads,
+		loops:         make(map[oteltrace.SpanID]*generateTurn),
+	}
+
+	var err error
+	if a.toolRequests, err = meter.Int64Counter("genkit/ai/tool/requests",
+		otelmetric.WithDescription("Counts calls to Genkit tools."),
+		otelmetric.WithUnit("1")); err != nil {
+		return nil, err
+	}
+	if a.toolLatency, err = meter.Float64Histogram("genkit/ai/tool/latency",
+		otelmetric.WithDescription("Latencies when calling Genkit tools."),
+		otelmetric.WithUnit("ms"),
+		otelmetric.WithExplicitBucketBoundaries(DefaultDurationBucketsMs...)); err != nil {
+		return nil, err
+	}
+	if a.turns, err = meter.Int64Histogram("genkit/ai/generate/turns",
+		otelmetric.WithDescription("Number of model turns taken by a generate call, including tool-calling iterations."),
+		otelmetric.WithUnit("{turn}"),
+		otelmetric.WithExplicitBucketBoundaries(1, 2, 3, 4, 5, 6, 8, 10, 15, 20, 30, 50)); err != nil {
+		return nil, err
+	}
+	if a.toolCalls, err = meter.Int64Histogram("genkit/ai/generate/tool_calls",
+		otelmetric.WithDescription("Number of tool calls made by a generate call across all its turns."),
+		otelmetric.WithUnit("{call}"),
+		otelmetric.WithExplicitBucketBoundaries(0, 1, 2, 3, 4, 5, 10, 15, 20, 30, 50, 100)); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// OnStart implements trace.SpanProcessor.
+func (a *agentTelemetry) OnStart(_ context.Context, span trace.ReadWriteSpan) {
+	spanType := stringAttribute(span.Attributes(), genkitTypeAttr)
+	if spanType == "" {
+		return // Not a Genkit span
+	}
+	parentID := span.Parent().SpanID()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if isGenerateSpan(spanType, span.Name()) {
+		turn := 1
+		if parent, ok := a.loops[parentID]; ok {
+			turn = parent.turn + 1
+		}
+		a.loops[span.SpanContext().SpanID()] = &generateTurn{parent: parentID, turn: turn}
+		return
+	}
+
+	// The subtype is only known when the span ends, so tool calls are
+	// recognized by the tool requests of the model call before them
+	parent, ok := a.loops[parentID]
+	if !ok || spanType != "action" || !parent.toolRequests[span.Name()] {
+		return
+	}
+	span.SetAttributes(
+		attribute.String(toolModelAttr, parent.modelName),
+		attribute.String(toolModelSpanIDAttr, parent.model.SpanID().String()),
+		attribute.Int(generateTurnAttr, parent.turn),
+	)
+	span.AddLink(oteltrace.Link{SpanContext: parent.model})
+}
+
+// OnEnd implements trace.SpanProcessor.
+func (a *agentTelemetry) OnEnd(span trace.ReadOnlySpan) {
+	attrs := span.Attributes()
+	spanType := stringAttribute(attrs, genkitTypeAttr)
+	if spanType == "" {
+		return // Not a Genkit span
+	}
+	parentID := span.Parent().SpanID()
+
+	switch {
+	case isGenerateSpan(spanType, span.Name()):
+		a.endGenerate(span)
+	case spanType == "action" && stringAttribute(attrs, genkitSubtypeAttr) == "model":
+		a.mu.Lock()
+		if parent, ok := a.loops[parentID]; ok {
+			parent.model = span.SpanContext()
+			parent.modelName = stringAttribute(attrs, genkitNameAttr)
+			parent.toolRequests = make(map[string]bool)
+			output := modelResponse(a.payloads, span)
+			for _, req := range output.ToolRequests() {
+				parent.toolRequests[req.Name] = true
+			}
+		}
+		a.mu.Unlock()
+	case spanType == "action" && stringAttribute(attrs, genkitSubtypeAttr) == "tool":
+		a.mu.Lock()
+		if parent, ok := a.loops[parentID]; ok {
+			parent.toolCalls++
+		}
+		a.mu.Unlock()
+		a.recordTool(spanContext(span), span)
+	}
+}
+
+// Shutdown implements trace.SpanProcessor.
+func (a *agentTelemetry) Shutdown(context.Context) error { return nil }
+
+// ForceFlush implements trace.SpanProcessor.
+func (a *agentTelemetry) ForceFlush(context.Context) error { return nil }
+
+// endGenerate folds a finished turn into the enclosing one, or records the
+// loop metrics when it is the outermost generate span.
+func (a *agentTelemetry) endGenerate(span trace.ReadOnlySpan) {
+	a.mu.Lock()
+	id := span.SpanContext().SpanID()
+	loop, ok := a.loops[id]
+	delete(a.loops, id)
+	if !ok {
+		a.mu.Unlock()
+		return
+	}
+	loop.turns++
+	if parent, ok := a.loops[loop.parent]; ok {
+		parent.turns += loop.turns
+		parent.toolCalls += loop.toolCalls
+		a.mu.Unlock()
+		return
+	}
+	a.mu.Unlock()
+
+	attrs := span.Attributes()
+	status := "success"
+	if stringAttribute(attrs, genkitStateAttr) == "error" {
+		status = "failure"
+	}
+	featureName := featureNameFromPath(stringAttribute(attrs, genkitPathAttr))
+	if featureName == "<unknown>" {
+		featureName = "generate"
+	}
+
+	dims := []attribute.KeyValue{
+		attribute.String("featureName", featureName),
+		attribute.String("modelName", truncate(loop.modelName, 1024)),
+		attribute.String("status", status),
+		attribute.String("source", "go"),
+		attribute.String("sourceVersion", genkitSourceVersion()),
+	}
+	dims = append(dims, promotedAttributes(attrs, a.dimensionKeys)...)
+
+	ctx := spanContext(span)
+	opt := otelmetric.WithAttributes(dims...)
+	a.turns.Record(ctx, int64(loop.turns), opt)
+	a.toolCalls.Record(ctx, int64(loop.toolCalls), opt)
+}
+
+// recordTool records request and latency metrics for a tool call.
+func (a *agentTelemetry) recordTool(ctx context.Context, span trace.ReadOnlySpan) {
+	attrs := span.Attributes()
+	path := stringAttribute(attrs, genkitPathAttr)
+	featureName := featureNameFromPath(path)
+	if featureName == "<unknown>" {
+		featureName = "generate"
+	}
+
+	dims := []attribute.KeyValue{
+		attribute.String("toolName", truncate(stringAttribute(attrs, genkitNameAttr), 1024)),
+		attribute.String("featureName", featureName),
+		attribute.String("path", path),
+		attribute.String("source", "go"),
+		attribute.String("sourceVersion", genkitSourceVersion()),
+	}
+	dims = append(dims, promotedAttributes(attrs, a.dimensionKeys)...)
+
+	if errorName := spanErrorName(span); errorName != "" {
+		dims = append(dims, attribute.String("status", "failure"), attribute.String("error", errorName))
+	} else {
+		dims = append(dims, attribute.String("status", "success"))
+	}
+
+	opt := otelmetric.WithAttributes(dims...)
+	a.toolRequests.Add(ctx, 1, opt)
+	a.toolLatency.Record(ctx, spanLatencyMs(span), opt)
+}
+
+// isGenerateSpan reports whether a span is one turn of a Genkit generate call.
+func isGenerateSpan(spanType, name string) bool {
+	return spanType == "util" && name == "generate"
+}
@@ -0,0 +1,87 @@
+package opentelemetry_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestAgentLoopMetrics(t *testing.T) {
+	tel, g := newTestGenkit(t)
+	weather := genkit.DefineTool(g, "weather", "Returns the weather", func(ctx *ai.ToolContext, city string) (string, error) {
+		return "sunny", nil
+	})
+
+	// The first turn requests two tools, the second one more, the third answers
+	cities := [][]string{{"Paris", "Rome"}, {"Oslo"}}
+	genkit.DefineModel(g, "test/agent", &ai.ModelOptions{Supports: &ai.ModelSupports{Multiturn: true, Tools: true}},
+		func(ctx context.Context, req *ai.ModelRequest, cb ai.ModelStreamCallback) (*ai.ModelResponse, error) {
+			turn := 0
+			for _, msg := range req.Messages {
+				if msg.Role == ai.RoleTool {
+					turn++
+				}
+			}
+			if turn == len(cities) {
+				return &ai.ModelResponse{Message: ai.NewModelTextMessage("sunny everywhere")}, nil
+			}
+			var parts []*ai.Part
+			for i, city := range cities[turn] {
+				parts = append(parts, ai.NewToolRequestPart(&ai.ToolRequest{
+					Name: "weather", Input: city, Ref: fmt.Sprintf("call-%d-%d", turn, i),
+				}))
+			}
+			return &ai.ModelResponse{Message: ai.NewModelMessage(parts...)}, nil
+		})
+	flow := genkit.DefineFlow(g, "agentFlow", func(ctx context.Context, input string) (string, error) {
+		resp, err := genkit.Generate(ctx, g, ai.WithModelName("test/agent"), ai.WithPrompt(input), ai.WithTools(weather))
+		if err != nil {
+			return "", err
+		}
+		return resp.Text(), nil
+	})
+	runFlow(t, flow, "weather?")
+
+	loop := []attribute.KeyValue{
+		attribute.String("featureName", "agentFlow"),
+		attribute.String("modelName", "test/agent"),
+		attribute.String("status", "success"),
+	}
+	if turns := tel.AssertMetric("genkit/ai/generate/turns", 1, loop...); turns.Value != 3 {
+		t.Errorf("genkit/ai/generate/turns sum = %v, want 3", turns.Value)
+	}
+	if calls := tel.AssertMetric("genkit/ai/generate/tool_calls", 1, loop...); calls.Value != 3 {
+		t.Errorf("genkit/ai/generate/tool_calls sum = %v, want 3", calls.Value)
+	}
+	// Each turn nests in the previous one, so the tool paths differ by turn
+	for path, calls := range map[string]float64{
+		"/{agentFlow,t:flow}/{generate,t:util}/{weather,t:action,s:tool}":                   2,
+		"/{agentFlow,t:flow}/{generate,t:util}/{generate,t:util}/{weather,t:action,s:tool}": 1,
+	} {
+		tool := []attribute.KeyValue{attribute.String("toolName", "weather"), attribute.String("path", path)}
+		tel.AssertMetric("genkit/ai/tool/requests", calls, tool...)
+		tel.AssertMetric("genkit/ai/tool/latency", calls, tool...)
+	}
+
+	// Tool spans are linked to the model call that requested them
+	turns := map[int64]int{}
+	for _, span := range tel.FindFlowTrace("agentFlow") {
+		if span.Name != "weather" {
+			continue
+		}
+		if got := attributeValue(span, "genkit.tool.model").AsString(); got != "test/agent" {
+			t.Errorf("tool span genkit.tool.model = %q, want test/agent", got)
+		}
+		if len(span.Links) != 1 {
+			t.Errorf("tool span has %d links, want one to the model span", len(span.Links))
+		}
+		turns[attributeValue(span, "genkit.generate.turn").AsInt64()]++
+	}
+	if turns[1] != 2 || turns[2] != 1 {
+		t.Errorf("tool calls by turn = %v, want 2 in turn 1 and 1 in turn 2", turns)
+	}
+}
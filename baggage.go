@@ -0,0 +1,59 @@
+package opentelemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+// baggageSpanProcessor copies allowlisted baggage members onto every span
+// started under a context carrying them.
+type baggageSpanProcessor struct {
+	keys []string
+}
+
+// newBaggageSpanProcessor creates a span processor promoting the given baggage keys.
+func newBaggageSpanProcessor(keys []string) *baggageSpanProcessor {
+	return &baggageSpanProcessor{keys: keys}
+}
+
+// OnStart implements trace.SpanProcessor.
+func (p *baggageSpanProcessor) OnStart(ctx context.Context, span trace.ReadWriteSpan) {
+	bag := baggage.FromContext(ctx)
+	if bag.Len() == 0 {
+		return
+	}
+
+	var attrs []attribute.KeyValue
+	for _, key := range p.keys {
+		if member := bag.Member(key); member.Key() != "" {
+			attrs = append(attrs, attribute.String(key, member.Value()))
+		}
+	}
+	if len(attrs) > 0 {
+		span.SetAttributes(attrs...)
+	}
+}
+
+// OnEnd implements trace.SpanProcessor.
+func (p *baggageSpanProcessor) OnEnd(trace.ReadOnlySpan) {}
+
+// Shutdown implements trace.SpanProcessor.
+func (p *baggageSpanProcessor) Shutdown(context.Context) error { return nil }
+
+// ForceFlush implements trace.SpanProcessor.
+func (p *baggageSpanProcessor) ForceFlush(context.Context) error { return nil }
+
+// promotedAttributes returns the span attributes for the given keys, so values
+// promoted from baggage can be used as metric dimensions.
+func promotedAttributes(attrs []attribute.KeyValue, keys []string) []attribute.KeyValue {
+	var promoted []attribute.KeyValue
+	for _, key := range keys {
+		if value := stringAttribute(attrs, key); value != "" {
+			promoted = append(promoted, attribute.String(key, value))
+		}
+	}
+	return promoted
+}
@@ -0,0 +1,45 @@
+package opentelemetry_test
+
+import (
+	"context"
+	"testing"
+
+	opentelemetry "github.com/xavidop/genkit-opentelemetry-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+)
+
+func TestBaggagePromotion(t *testing.T) {
+	tel, g := newTestGenkit(t, opentelemetry.Config{BaggageAttributes: []string{"tenant.id"}})
+	flow := defineGenerateFlow(g, "tenantFlow")
+
+	bag, err := baggage.Parse("tenant.id=acme,user.email=ada%40example.com")
+	if err != nil {
+		t.Fatalf("baggage.Parse() error = %v", err)
+	}
+	if _, err := flow.Run(baggage.ContextWithBaggage(context.Background(), bag), "hi"); err != nil {
+		t.Fatalf("flow.Run() error = %v", err)
+	}
+
+	tenant := attribute.String("tenant.id", "acme")
+	for _, name := range []string{"tenantFlow", testModel} {
+		span := tel.AssertSpan(name, tenant)
+		if hasAttribute(span, "user.email") {
+			t.Errorf("span %q has user.email, which is not allowlisted", name)
+		}
+	}
+	for _, name := range []string{"genkit/feature/requests", "genkit/ai/generate/requests"} {
+		point := tel.AssertMetric(name, 1, tenant)
+		if _, ok := point.Attributes.Value("user.email"); ok {
+			t.Errorf("metric %s has user.email, which is not allowlisted", name)
+		}
+	}
+
+	// Runs without baggage have no tenant dimension
+	tel.Reset()
+	runFlow(t, flow, "hi")
+	point := tel.AssertMetric("genkit/feature/requests", 1, attribute.String("name", "tenantFlow"))
+	if _, ok := point.Attributes.Value("tenant.id"); ok {
+		t.Error("genkit/feature/requests has tenant.id without baggage")
+	}
+}
@@ -0,0 +1,146 @@
+package opentelemetry
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/firebase/genkit/go/genkit"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// debugAttr marks the root span of a flow traced because of a debug override.
+const debugAttr = "genkit.debug"
+
+// Debug override durations.
+const (
+	defaultDebugOverrideTTL = 15 * time.Minute
+	maxDebugOverrideTTL     = 24 * time.Hour
+)
+
+// DebugOverride turns on full tracing for one flow until it expires.
+type DebugOverride struct {
+	Flow      string    `json:"flow"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// debugOverrides are the active per-flow debug overrides.
+type debugOverrides struct {
+	mu    sync.RWMutex
+	flows map[string]time.Time
+
+	// Genkit instance the flows are registered with, see RegisterFlows
+	registry atomic.Pointer[genkit.Genkit]
+}
+
+// newDebugOverrides creates an empty set of overrides.
+func newDebugOverrides() *debugOverrides {
+	return &debugOverrides{flows: map[string]time.Time{}}
+}
+
+// enable turns on debugging of flow for ttl, replacing any earlier expiry.
+func (d *debugOverrides) enable(flow string, ttl time.Duration) DebugOverride {
+	override := DebugOverride{Flow: flow, ExpiresAt: time.Now().Add(ttl)}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.flows[flow] = override.ExpiresAt
+	return override
+}
+
+// disable turns off debugging of flow and reports whether it was on.
+func (d *debugOverrides) disable(flow string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	expiresAt, ok := d.flows[flow]
+	delete(d.flows, flow)
+	return ok && time.Now().Before(expiresAt)
+}
+
+// list returns the active overrides sorted by flow, removing expired ones.
+func (d *debugOverrides) list() []DebugOverride {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	overrides := []DebugOverride{}
+	for flow, expiresAt := range d.flows {
+		if !now.Before(expiresAt) {
+			delete(d.flows, flow)
+			continue
+		}
+		overrides = append(overrides, DebugOverride{Flow: flow, ExpiresAt: expiresAt})
+	}
+	slices.SortFunc(overrides, func(a, b DebugOverride) int {
+		return strings.Compare(a.Flow, b.Flow)
+	})
+	return overrides
+}
+
+// active reports whether flow is being debugged.
+func (d *debugOverrides) active(flow string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if len(d.flows) == 0 {
+		return false
+	}
+	expiresAt, ok := d.flows[flow]
+	return ok && time.Now().Before(expiresAt)
+}
+
+// isFlow reports whether name is a flow registered with the Genkit instance
+// passed to RegisterFlows. Flows are looked up on each call, so flows defined
+// after RegisterFlows are found too.
+func (d *debugOverrides) isFlow(name string) bool {
+	g := d.registry.Load()
+	if g == nil {
+		return false
+	}
+	for _, flow := range genkit.ListFlows(g) {
+		if flow.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// debugSampler samples every trace of a debugged flow and leaves other
+// decisions to the configured sampler.
+type debugSampler struct {
+	trace.Sampler
+	overrides *debugOverrides
+}
+
+// ShouldSample implements trace.Sampler. Genkit only sets genkit:type when a
+// span starts, so flows are recognized by the span name of their action, which
+// must be a registered flow, so models and tools of the same name do not
+// match. The root span of a debugged flow is always marked, but only forced
+// into the sample when its parent is not sampled already. Spans of the flow
+// follow the root's decision through the parent-based default sampler.
+func (s *debugSampler) ShouldSample(parameters trace.SamplingParameters) trace.SamplingResult {
+	if stringAttribute(parameters.Attributes, genkitTypeAttr) != "action" ||
+		!s.overrides.active(parameters.Name) || !s.overrides.isFlow(parameters.Name) {
+		return s.Sampler.ShouldSample(parameters)
+	}
+
+	marker := attribute.Bool(debugAttr, true)
+	parent := oteltrace.SpanContextFromContext(parameters.ParentContext)
+	if !parent.IsValid() || !parent.IsSampled() {
+		return trace.SamplingResult{
+			Decision:   trace.RecordAndSample,
+			Attributes: []attribute.KeyValue{marker},
+			Tracestate: parent.TraceState(),
+		}
+	}
+	result := s.Sampler.ShouldSample(parameters)
+	result.Attributes = append(slices.Clone(result.Attributes), marker)
+	return result
+}
+
+// Description implements trace.Sampler.
+func (s *debugSampler) Description() string {
+	return "DebugOverrides{" + s.Sampler.Description() + "}"
+}
@@ -0,0 +1,55 @@
+package opentelemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+// SpanEnricher adds attributes to a Genkit span as it starts, e.g.
+// request-scoped values read from ctx. It runs on every Genkit span, so it
+// should be fast.
+type SpanEnricher func(ctx context.Context, span trace.ReadWriteSpan)
+
+// enrichingSpanProcessor sets static attributes and runs an enricher on
+// every Genkit span as it starts. Other spans, such as HTTP server spans, are
+// left unchanged.
+type enrichingSpanProcessor struct {
+	attrs    []attribute.KeyValue
+	enricher SpanEnricher
+}
+
+// newEnrichingSpanProcessor creates a span processor setting attrs and
+// calling enricher, which may be nil.
+func newEnrichingSpanProcessor(attrs map[string]string, enricher SpanEnricher) *enrichingSpanProcessor {
+	p := &enrichingSpanProcessor{enricher: enricher}
+	for key, value := range attrs {
+		p.attrs = append(p.attrs, attribute.String(key, value))
+	}
+	return p
+}
+
+// OnStart implements trace.SpanProcessor. The enricher runs after the static
+// attributes are set, so it can override them.
+func (p *enrichingSpanProcessor) OnStart(ctx context.Context, span trace.ReadWriteSpan) {
+	// Genkit sets the span type as the span starts
+	if stringAttribute(span.Attributes(), genkitTypeAttr) == "" {
+		return
+	}
+	if len(p.attrs) > 0 {
+		span.SetAttributes(p.attrs...)
+	}
+	if p.enricher != nil {
+		p.enricher(ctx, span)
+	}
+}
+
+// OnEnd implements trace.SpanProcessor.
+func (p *enrichingSpanProcessor) OnEnd(trace.ReadOnlySpan) {}
+
+// Shutdown implements trace.SpanProcessor.
+func (p *enrichingSpanProcessor) Shutdown(context.Context) error { return nil }
+
+// ForceFlush implements trace.SpanProcessor.
+func (p *enrichingSpanProcessor) ForceFlush(context.Context) error { return nil }
@@ -0,0 +1,53 @@
+package opentelemetry_test
+
+import (
+	"context"
+	"testing"
+
+	opentelemetry "github.com/xavidop/genkit-opentelemetry-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// requestIDKey holds a request ID in a context.
+type requestIDKey struct{}
+
+func TestSpanEnrichment(t *testing.T) {
+	tel, g := newTestGenkit(t, opentelemetry.Config{
+		SpanAttributes: map[string]string{
+			"deployment.environment": "production",
+			"feature.variant":        "control",
+		},
+		SpanEnricher: func(ctx context.Context, span sdktrace.ReadWriteSpan) {
+			if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+				span.SetAttributes(attribute.String("request.id", id))
+			}
+			span.SetAttributes(attribute.String("feature.variant", "treatment"))
+		},
+	})
+	flow := defineGenerateFlow(g, "enrichedFlow")
+
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "req-42")
+	if _, err := flow.Run(ctx, "hi"); err != nil {
+		t.Fatalf("flow.Run() error = %v", err)
+	}
+	for _, name := range []string{"enrichedFlow", testModel} {
+		tel.AssertSpan(name,
+			attribute.String("deployment.environment", "production"),
+			attribute.String("request.id", "req-42"),
+			// The enricher overrides static attributes
+			attribute.String("feature.variant", "treatment"),
+		)
+	}
+
+	// Spans without Genkit attributes are left unchanged
+	_, span := otel.Tracer("test").Start(ctx, "plain")
+	span.End()
+	plain := tel.AssertSpan("plain")
+	for _, key := range []string{"deployment.environment", "request.id", "feature.variant"} {
+		if hasAttribute(plain, key) {
+			t.Errorf("plain span has %s, want it left unchanged", key)
+		}
+	}
+}
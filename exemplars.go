@@ -0,0 +1,39 @@
+package opentelemetry
+
+import (
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/sdk/metric/exemplar"
+)
+
+// ExemplarFilter selects which measurements are sampled as exemplars.
+type ExemplarFilter string
+
+// Exemplar filters accepted by Config.ExemplarFilter, matching the values of
+// OTEL_METRICS_EXEMPLAR_FILTER.
+const (
+	// ExemplarFilterTraceBased samples measurements recorded inside a sampled span.
+	ExemplarFilterTraceBased ExemplarFilter = "trace_based"
+
+	// ExemplarFilterAlwaysOn samples every measurement.
+	ExemplarFilterAlwaysOn ExemplarFilter = "always_on"
+
+	// ExemplarFilterAlwaysOff disables exemplars.
+	ExemplarFilterAlwaysOff ExemplarFilter = "always_off"
+)
+
+// filter returns the SDK exemplar filter for f. Like the SDK's parsing of
+// OTEL_METRICS_EXEMPLAR_FILTER, names are matched case-insensitively.
+func (f ExemplarFilter) filter() (exemplar.Filter, error) {
+	switch ExemplarFilter(strings.ToLower(strings.TrimSpace(string(f)))) {
+	case ExemplarFilterTraceBased:
+		return exemplar.TraceBasedFilter, nil
+	case ExemplarFilterAlwaysOn:
+		return exemplar.AlwaysOnFilter, nil
+	case ExemplarFilterAlwaysOff:
+		return exemplar.AlwaysOffFilter, nil
+	default:
+		return nil, fmt.Errorf("unknown exemplar filter %q", f)
+	}
+}
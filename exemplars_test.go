@@ -0,0 +1,80 @@
+package opentelemetry
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// sampledSpanContext returns a context inside a sampled span.
+func sampledSpanContext() context.Context {
+	return oteltrace.ContextWithSpanContext(context.Background(), oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    oteltrace.TraceID{1},
+		SpanID:     oteltrace.SpanID{1},
+		TraceFlags: oteltrace.FlagsSampled,
+	}))
+}
+
+func TestExemplarFilter(t *testing.T) {
+	tests := []struct {
+		filter      ExemplarFilter
+		wantSampled bool // for a measurement inside a sampled span
+		wantOutside bool // for a measurement outside of spans
+	}{
+		{ExemplarFilterTraceBased, true, false},
+		{ExemplarFilterAlwaysOn, true, true},
+		{ExemplarFilterAlwaysOff, false, false},
+		{"Always_On", true, true},
+		{" TRACE_BASED ", true, false},
+		{"ALWAYS_OFF", false, false},
+	}
+	for _, tt := range tests {
+		filter, err := tt.filter.filter()
+		if err != nil {
+			t.Errorf("%q.filter() error = %v", tt.filter, err)
+			continue
+		}
+		if got := filter(sampledSpanContext()); got != tt.wantSampled {
+			t.Errorf("%q.filter() inside a sampled span = %v, want %v", tt.filter, got, tt.wantSampled)
+		}
+		if got := filter(context.Background()); got != tt.wantOutside {
+			t.Errorf("%q.filter() outside of spans = %v, want %v", tt.filter, got, tt.wantOutside)
+		}
+	}
+
+	if _, err := ExemplarFilter("sometimes").filter(); err == nil {
+		t.Error(`"sometimes".filter() succeeded, want an error`)
+	}
+}
+
+func TestExemplarFilterPrecedence(t *testing.T) {
+	tests := []struct {
+		name   string
+		config ExemplarFilter
+		env    string
+		want   bool // exemplar for a measurement outside of spans
+	}{
+		{"SDK default", "", "", false},
+		{"environment", "", "ALWAYS_ON", true},
+		{"config", "Always_On", "", true},
+		{"config over environment", ExemplarFilterAlwaysOff, "always_on", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OTEL_METRICS_EXEMPLAR_FILTER", tt.env)
+			provider, reader := newManualMeterProvider(t, Config{ExemplarFilter: tt.config})
+			histogram, err := provider.Meter("test").Float64Histogram("latency")
+			if err != nil {
+				t.Fatal(err)
+			}
+			histogram.Record(context.Background(), 10)
+
+			data := collectMetric(t, reader, "latency").Data.(metricdata.Histogram[float64])
+			if got := len(data.DataPoints[0].Exemplars) > 0; got != tt.want {
+				t.Errorf("exemplar recorded = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
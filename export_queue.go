@@ -0,0 +1,460 @@
+package opentelemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+// defaultExportQueueMaxBytes is the default size limit of each signal's export
+// queue on disk.
+const defaultExportQueueMaxBytes int64 = 100 << 20
+
+// exportQueueReplayInterval is how often spooled batches are retried while
+// nothing new is being exported, and the longest backoff after failures.
+const exportQueueReplayInterval = 30 * time.Second
+
+// exportQueueMinBackoff is the wait before retrying after a failed export,
+// doubled on each further failure.
+const exportQueueMinBackoff = time.Second
+
+// Batch files are named by sequence number so they sort in export order.
+const (
+	batchFileSuffix = ".batch"
+	batchFileFormat = "%020d" + batchFileSuffix
+	tmpFileSuffix   = ".tmp"
+)
+
+// diskQueue is a FIFO of encoded batches, one file each, in a directory. It is
+// not safe for concurrent use.
+type diskQueue struct {
+	dir      string
+	maxBytes int64
+
+	// Sequence numbers and sizes of the queued batches, oldest first
+	seqs  []uint64
+	sizes []int64
+	bytes int64
+	next  uint64
+}
+
+// openDiskQueue opens the queue in dir, creating the directory if needed.
+// Batches left by a previous process are kept; partially written ones are
+// removed.
+func openDiskQueue(dir string, maxBytes int64) (*diskQueue, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &diskQueue{dir: dir, maxBytes: maxBytes}
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasSuffix(name, tmpFileSuffix) {
+			_ = os.Remove(filepath.Join(dir, name))
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, batchFileSuffix), 10, 64)
+		if err != nil || !strings.HasSuffix(name, batchFileSuffix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		q.seqs = append(q.seqs, seq)
+		q.sizes = append(q.sizes, info.Size())
+		q.bytes += info.Size()
+	}
+	// ReadDir sorts by name and names are zero-padded, so seqs is ascending
+	if len(q.seqs) > 0 {
+		q.next = q.seqs[len(q.seqs)-1] + 1
+	}
+	return q, nil
+}
+
+// len returns the number of queued batches.
+func (q *diskQueue) len() int {
+	return len(q.seqs)
+}
+
+// push appends a batch, evicting the oldest batches if the queue would exceed
+// its size limit.
+func (q *diskQueue) push(data []byte) error {
+	size := int64(len(data))
+	if size > q.maxBytes {
+		return fmt.Errorf("batch of %d bytes exceeds the export queue limit of %d bytes", size, q.maxBytes)
+	}
+	for q.len() > 0 && q.bytes+size > q.maxBytes {
+		slog.Warn("Export queue full, dropping oldest batch", "dir", q.dir)
+		if err := q.pop(); err != nil {
+			return err
+		}
+	}
+
+	// Write to a temporary file first so a crash never leaves a partial batch
+	path := q.path(q.next)
+	tmp := path + tmpFileSuffix
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	q.seqs = append(q.seqs, q.next)
+	q.sizes = append(q.sizes, size)
+	q.bytes += size
+	q.next++
+	return nil
+}
+
+// front returns the oldest batch and its sequence number.
+func (q *diskQueue) front() ([]byte, uint64, error) {
+	data, err := os.ReadFile(q.path(q.seqs[0]))
+	return data, q.seqs[0], err
+}
+
+// pop removes the oldest batch.
+func (q *diskQueue) pop() error {
+	if err := os.Remove(q.path(q.seqs[0])); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	q.bytes -= q.sizes[0]
+	q.seqs = slices.Delete(q.seqs, 0, 1)
+	q.sizes = slices.Delete(q.sizes, 0, 1)
+	return nil
+}
+
+// path returns the file of the batch with the given sequence number.
+func (q *diskQueue) path(seq uint64) string {
+	return filepath.Join(q.dir, fmt.Sprintf(batchFileFormat, seq))
+}
+
+// queuedExport sends batches of T through export, spooling the batches that
+// fail to a disk queue. While batches are spooled, newer batches are spooled
+// behind them so order is preserved. Each send retries the oldest spooled
+// batch, and a background loop replays the rest. After a failure, exports
+// back off before the endpoint is tried again.
+type queuedExport[T any] struct {
+	signal string
+	queue  *diskQueue
+	encode func(T) ([]byte, error)
+	decode func([]byte) (T, error)
+	export func(context.Context, T) error
+
+	// Number and total size of queued batches, readable without waiting for
+	// the queue
+	batches atomic.Int64
+	bytes   atomic.Int64
+
+	// Guards the queue and the backoff, but is not held while exporting
+	mu       sync.Mutex
+	failures int
+	retryAt  time.Time
+
+	// Held while replaying, so each batch is replayed once and in order
+	replayMu sync.Mutex
+
+	wake     chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// newQueuedExport opens the queue of a signal and starts replaying it.
+func newQueuedExport[T any](
+	signal, dir string,
+	maxBytes int64,
+	encode func(T) ([]byte, error),
+	decode func([]byte) (T, error),
+	export func(context.Context, T) error,
+) (*queuedExport[T], error) {
+	queue, err := openDiskQueue(filepath.Join(dir, signal), maxBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s export queue: %w", signal, err)
+	}
+	if queue.len() > 0 {
+		slog.Info("Found spooled telemetry from a previous run", "signal", signal, "batches", queue.len())
+	}
+
+	q := &queuedExport[T]{
+		signal: signal,
+		queue:  queue,
+		encode: encode,
+		decode: decode,
+		export: export,
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+	}
+	q.updateSize()
+	go q.replayLoop()
+	if queue.len() > 0 {
+		q.wakeReplay()
+	}
+	return q, nil
+}
+
+// send exports batch, or spools it if the endpoint is unavailable or older
+// batches are waiting. Errors are only returned if the batch could not be
+// spooled either.
+func (q *queuedExport[T]) send(ctx context.Context, batch T) error {
+	q.mu.Lock()
+	direct := q.queue.len() == 0 && !q.backingOff()
+	q.mu.Unlock()
+
+	if direct {
+		err := q.export(ctx, batch)
+		q.exported(err)
+		if err == nil {
+			return nil
+		}
+		slog.Debug("Export failed, spooling batch to disk", "signal", q.signal, "error", err)
+	}
+
+	data, err := q.encode(batch)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s batch: %w", q.signal, err)
+	}
+	q.mu.Lock()
+	err = q.queue.push(data)
+	q.updateSize()
+	retry := !direct && !q.backingOff()
+	q.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to spool %s batch: %w", q.signal, err)
+	}
+
+	// Retry the oldest batch, unless a replay is already running
+	if retry && q.replayMu.TryLock() {
+		q.replay(ctx, 1)
+		q.replayMu.Unlock()
+	}
+	return nil
+}
+
+// backingOff reports whether exports wait after a failure. The caller must
+// hold q.mu.
+func (q *queuedExport[T]) backingOff() bool {
+	return time.Now().Before(q.retryAt)
+}
+
+// exported records the outcome of an export, backing off after failures and
+// waking the replay once the endpoint has recovered.
+func (q *queuedExport[T]) exported(err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err == nil {
+		recovered := q.failures > 0
+		q.failures = 0
+		q.retryAt = time.Time{}
+		if recovered && q.queue.len() > 0 {
+			q.wakeReplay()
+		}
+		return
+	}
+
+	q.failures++
+	backoff := exportQueueReplayInterval
+	if q.failures < 6 {
+		backoff = min(exportQueueMinBackoff<<(q.failures-1), exportQueueReplayInterval)
+	}
+	q.retryAt = time.Now().Add(backoff)
+}
+
+// wakeReplay makes the background loop replay the queue now.
+func (q *queuedExport[T]) wakeReplay() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// updateSize publishes the size of the queue. The caller must hold q.mu,
+// except during construction.
+func (q *queuedExport[T]) updateSize() {
+	q.batches.Store(int64(q.queue.len()))
+	q.bytes.Store(q.queue.bytes)
+}
+
+// spooled returns the number of batches waiting in the queue.
+func (q *queuedExport[T]) spooled() int64 {
+	return q.batches.Load()
+}
+
+// fill returns the fraction of the queue's size limit in use.
+func (q *queuedExport[T]) fill() float64 {
+	return float64(q.bytes.Load()) / float64(q.queue.maxBytes)
+}
+
+// flush replays every spooled batch, regardless of the backoff.
+func (q *queuedExport[T]) flush(ctx context.Context) {
+	q.replayMu.Lock()
+	defer q.replayMu.Unlock()
+	q.replay(ctx, 0)
+}
+
+// replay exports up to limit spooled batches oldest first, or all of them if
+// limit is 0. It stops at the first failure so order is preserved. The caller
+// must hold q.replayMu.
+func (q *queuedExport[T]) replay(ctx context.Context, limit int) {
+	replayed := 0
+	defer func() {
+		if replayed > 0 {
+			slog.Info("Replayed spooled telemetry", "signal", q.signal, "batches", replayed, "remaining", q.spooled())
+		}
+	}()
+
+	for limit == 0 || replayed < limit {
+		q.mu.Lock()
+		if q.queue.len() == 0 {
+			q.mu.Unlock()
+			return
+		}
+		data, seq, err := q.queue.front()
+		if err == nil {
+			var batch T
+			if batch, err = q.decode(data); err == nil {
+				q.mu.Unlock()
+				if err := q.export(ctx, batch); err != nil {
+					q.exported(err)
+					return
+				}
+				q.exported(nil)
+				replayed++
+				q.mu.Lock()
+			} else {
+				slog.Error("Failed to decode spooled batch, dropping it", "signal", q.signal, "error", err)
+			}
+		} else {
+			slog.Error("Failed to read spooled batch, dropping it", "signal", q.signal, "error", err)
+		}
+
+		// The batch may have been evicted by a push while it was exported
+		var popErr error
+		if q.queue.len() > 0 && q.queue.seqs[0] == seq {
+			popErr = q.queue.pop()
+		}
+		q.updateSize()
+		q.mu.Unlock()
+		if err := popErr; err != nil {
+			slog.Error("Failed to remove spooled batch", "signal", q.signal, "error", err)
+			return
+		}
+	}
+}
+
+// replayLoop replays spooled batches in the background until close is
+// called: when woken, once a backoff has passed, and periodically so batches
+// are delivered even if nothing new is exported.
+func (q *queuedExport[T]) replayLoop() {
+	timer := time.NewTimer(exportQueueReplayInterval)
+	defer timer.Stop()
+	for {
+		q.mu.Lock()
+		wait := exportQueueReplayInterval
+		if backoff := time.Until(q.retryAt); backoff > 0 && backoff < wait {
+			wait = backoff
+		}
+		q.mu.Unlock()
+		timer.Reset(wait)
+
+		select {
+		case <-q.stop:
+			return
+		case <-q.wake:
+		case <-timer.C:
+		}
+
+		q.mu.Lock()
+		skip := q.queue.len() == 0 || q.backingOff()
+		q.mu.Unlock()
+		if skip {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), exportQueueReplayInterval)
+		q.flush(ctx)
+		cancel()
+	}
+}
+
+// close stops the background replay. Spooled batches stay on disk for the
+// next process.
+func (q *queuedExport[T]) close() {
+	q.stopOnce.Do(func() { close(q.stop) })
+}
+
+// queuedSpanExporter is a span exporter spooling failed batches to disk.
+type queuedSpanExporter struct {
+	exporter trace.SpanExporter
+	queue    *queuedExport[[]trace.ReadOnlySpan]
+}
+
+// newQueuedSpanExporter wraps exporter with a disk queue in dir/traces.
+func newQueuedSpanExporter(exporter trace.SpanExporter, dir string, maxBytes int64) (*queuedSpanExporter, error) {
+	queue, err := newQueuedExport(signalTraces, dir, maxBytes, encodeSpans, decodeSpans, exporter.ExportSpans)
+	if err != nil {
+		return nil, err
+	}
+	return &queuedSpanExporter{exporter: exporter, queue: queue}, nil
+}
+
+// ExportSpans implements trace.SpanExporter.
+func (e *queuedSpanExporter) ExportSpans(ctx context.Context, spans []trace.ReadOnlySpan) error {
+	return e.queue.send(ctx, spans)
+}
+
+// Shutdown implements trace.SpanExporter.
+func (e *queuedSpanExporter) Shutdown(ctx context.Context) error {
+	e.queue.close()
+	return e.exporter.Shutdown(ctx)
+}
+
+// queuedMetricExporter is a metric exporter spooling failed exports to disk.
+type queuedMetricExporter struct {
+	metric.Exporter
+	queue *queuedExport[*metricdata.ResourceMetrics]
+}
+
+// newQueuedMetricExporter wraps exporter with a disk queue in dir/metrics.
+func newQueuedMetricExporter(exporter metric.Exporter, dir string, maxBytes int64) (*queuedMetricExporter, error) {
+	queue, err := newQueuedExport(signalMetrics, dir, maxBytes, encodeMetrics, decodeMetrics, exporter.Export)
+	if err != nil {
+		return nil, err
+	}
+	return &queuedMetricExporter{Exporter: exporter, queue: queue}, nil
+}
+
+// Export implements metric.Exporter.
+func (e *queuedMetricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	return e.queue.send(ctx, rm)
+}
+
+// ForceFlush implements metric.Exporter.
+func (e *queuedMetricExporter) ForceFlush(ctx context.Context) error {
+	e.queue.flush(ctx)
+	return e.Exporter.ForceFlush(ctx)
+}
+
+// Shutdown implements metric.Exporter.
+func (e *queuedMetricExporter) Shutdown(ctx context.Context) error {
+	e.queue.close()
+	return e.Exporter.Shutdown(ctx)
+}
@@ -0,0 +1,905 @@
+package opentelemetry
+
+import (
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// Spooled batches are OTLP protobuf messages, TracesData for spans and
+// MetricsData for metrics, so they hold what an OTLP export would send. The
+// SDK only converts its types to OTLP inside its exporters, so the conversions
+// are done here. Child span counts have no OTLP field and are not kept.
+
+// valueTypeMetadataKey marks metrics with int64 values in the OTLP metric
+// metadata, as histograms and empty sums carry no value type of their own.
+const valueTypeMetadataKey = "genkit.otel.value_type"
+
+// encodeSpans serializes a batch of spans.
+func encodeSpans(spans []trace.ReadOnlySpan) ([]byte, error) {
+	type scopeKey struct {
+		resource *resource.Resource
+		scope    instrumentation.Scope
+	}
+
+	// Spans of a batch share few resources and scopes, so group them
+	data := &tracepb.TracesData{}
+	resources := map[*resource.Resource]*tracepb.ResourceSpans{}
+	scopes := map[scopeKey]*tracepb.ScopeSpans{}
+	for _, span := range spans {
+		res := span.Resource()
+		resourceSpans, ok := resources[res]
+		if !ok {
+			resourceSpans = &tracepb.ResourceSpans{Resource: encodeResource(res), SchemaUrl: res.SchemaURL()}
+			resources[res] = resourceSpans
+			data.ResourceSpans = append(data.ResourceSpans, resourceSpans)
+		}
+
+		key := scopeKey{resource: res, scope: span.InstrumentationScope()}
+		scopeSpans, ok := scopes[key]
+		if !ok {
+			scopeSpans = &tracepb.ScopeSpans{Scope: encodeScope(key.scope), SchemaUrl: key.scope.SchemaURL}
+			scopes[key] = scopeSpans
+			resourceSpans.ScopeSpans = append(resourceSpans.ScopeSpans, scopeSpans)
+		}
+		scopeSpans.Spans = append(scopeSpans.Spans, encodeSpan(span))
+	}
+	return proto.Marshal(data)
+}
+
+// encodeSpan converts a span to OTLP.
+func encodeSpan(span trace.ReadOnlySpan) *tracepb.Span {
+	sc := span.SpanContext()
+	traceID, spanID := sc.TraceID(), sc.SpanID()
+	encoded := &tracepb.Span{
+		TraceId:                traceID[:],
+		SpanId:                 spanID[:],
+		TraceState:             sc.TraceState().String(),
+		Flags:                  encodeFlags(sc.TraceFlags(), span.Parent().IsRemote()),
+		Name:                   span.Name(),
+		Kind:                   tracepb.Span_SpanKind(span.SpanKind()),
+		StartTimeUnixNano:      encodeTime(span.StartTime()),
+		EndTimeUnixNano:        encodeTime(span.EndTime()),
+		Attributes:             encodeAttributes(span.Attributes()),
+		DroppedAttributesCount: uint32(span.DroppedAttributes()),
+		DroppedEventsCount:     uint32(span.DroppedEvents()),
+		DroppedLinksCount:      uint32(span.DroppedLinks()),
+		Status: &tracepb.Status{
+			Code:    encodeStatusCode(span.Status().Code),
+			Message: span.Status().Description,
+		},
+	}
+	if parent := span.Parent(); parent.HasSpanID() {
+		parentID := parent.SpanID()
+		encoded.ParentSpanId = parentID[:]
+	}
+	for _, event := range span.Events() {
+		encoded.Events = append(encoded.Events, &tracepb.Span_Event{
+			TimeUnixNano:           encodeTime(event.Time),
+			Name:                   event.Name,
+			Attributes:             encodeAttributes(event.Attributes),
+			DroppedAttributesCount: uint32(event.DroppedAttributeCount),
+		})
+	}
+	for _, link := range span.Links() {
+		traceID, spanID := link.SpanContext.TraceID(), link.SpanContext.SpanID()
+		encoded.Links = append(encoded.Links, &tracepb.Span_Link{
+			TraceId:                traceID[:],
+			SpanId:                 spanID[:],
+			TraceState:             link.SpanContext.TraceState().String(),
+			Attributes:             encodeAttributes(link.Attributes),
+			DroppedAttributesCount: uint32(link.DroppedAttributeCount),
+			Flags:                  encodeFlags(link.SpanContext.TraceFlags(), link.SpanContext.IsRemote()),
+		})
+	}
+	return encoded
+}
+
+// decodeSpans restores a batch of spans serialized by encodeSpans.
+func decodeSpans(data []byte) ([]trace.ReadOnlySpan, error) {
+	var traces tracepb.TracesData
+	if err := proto.Unmarshal(data, &traces); err != nil {
+		return nil, err
+	}
+
+	var spans []trace.ReadOnlySpan
+	for _, resourceSpans := range traces.ResourceSpans {
+		res, err := decodeResource(resourceSpans.Resource, resourceSpans.SchemaUrl)
+		if err != nil {
+			return nil, err
+		}
+		for _, scopeSpans := range resourceSpans.ScopeSpans {
+			scope, err := decodeScope(scopeSpans.Scope, scopeSpans.SchemaUrl)
+			if err != nil {
+				return nil, err
+			}
+			for _, span := range scopeSpans.Spans {
+				decoded, err := decodeSpan(span, res, scope)
+				if err != nil {
+					return nil, fmt.Errorf("span %q: %w", span.Name, err)
+				}
+				spans = append(spans, decoded)
+			}
+		}
+	}
+	return spans, nil
+}
+
+// decodeSpan converts an OTLP span back to a finished span.
+func decodeSpan(span *tracepb.Span, res *resource.Resource, scope instrumentation.Scope) (trace.ReadOnlySpan, error) {
+	remoteFlags := uint32(tracepb.SpanFlags_SPAN_FLAGS_CONTEXT_HAS_IS_REMOTE_MASK | tracepb.SpanFlags_SPAN_FLAGS_CONTEXT_IS_REMOTE_MASK)
+	sc, err := decodeSpanContext(span.TraceId, span.SpanId, span.TraceState, span.Flags&^remoteFlags)
+	if err != nil {
+		return nil, err
+	}
+	var parent oteltrace.SpanContext
+	if len(span.ParentSpanId) > 0 {
+		if parent, err = decodeSpanContext(span.TraceId, span.ParentSpanId, "", span.Flags); err != nil {
+			return nil, err
+		}
+	}
+	attrs, err := decodeAttributes(span.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded := tracetest.SpanStub{
+		Name:                 span.Name,
+		SpanContext:          sc,
+		Parent:               parent,
+		SpanKind:             oteltrace.SpanKind(span.Kind),
+		StartTime:            decodeTime(span.StartTimeUnixNano),
+		EndTime:              decodeTime(span.EndTimeUnixNano),
+		Attributes:           attrs,
+		Status:               trace.Status{Code: decodeStatusCode(span.Status.GetCode()), Description: span.Status.GetMessage()},
+		InstrumentationScope: scope,
+		Resource:             res,
+		DroppedAttributes:    int(span.DroppedAttributesCount),
+		DroppedEvents:        int(span.DroppedEventsCount),
+		DroppedLinks:         int(span.DroppedLinksCount),
+	}
+	for _, event := range span.Events {
+		attrs, err := decodeAttributes(event.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		decoded.Events = append(decoded.Events, trace.Event{
+			Name:                  event.Name,
+			Attributes:            attrs,
+			DroppedAttributeCount: int(event.DroppedAttributesCount),
+			Time:                  decodeTime(event.TimeUnixNano),
+		})
+	}
+	for _, link := range span.Links {
+		sc, err := decodeSpanContext(link.TraceId, link.SpanId, link.TraceState, link.Flags)
+		if err != nil {
+			return nil, err
+		}
+		attrs, err := decodeAttributes(link.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		decoded.Links = append(decoded.Links, trace.Link{
+			SpanContext:           sc,
+			Attributes:            attrs,
+			DroppedAttributeCount: int(link.DroppedAttributesCount),
+		})
+	}
+	return decoded.Snapshot(), nil
+}
+
+// encodeFlags returns the OTLP span flags of a span context, where remote
+// applies to the parent of a span and to the linked span of a link.
+func encodeFlags(flags oteltrace.TraceFlags, remote bool) uint32 {
+	encoded := uint32(flags) | uint32(tracepb.SpanFlags_SPAN_FLAGS_CONTEXT_HAS_IS_REMOTE_MASK)
+	if remote {
+		encoded |= uint32(tracepb.SpanFlags_SPAN_FLAGS_CONTEXT_IS_REMOTE_MASK)
+	}
+	return encoded
+}
+
+// decodeSpanContext converts OTLP IDs, trace state and span flags back to a
+// span context.
+func decodeSpanContext(traceID, spanID []byte, traceState string, flags uint32) (oteltrace.SpanContext, error) {
+	var config oteltrace.SpanContextConfig
+	if len(traceID) != 0 && len(traceID) != len(config.TraceID) {
+		return oteltrace.SpanContext{}, fmt.Errorf("invalid trace ID of %d bytes", len(traceID))
+	}
+	if len(spanID) != 0 && len(spanID) != len(config.SpanID) {
+		return oteltrace.SpanContext{}, fmt.Errorf("invalid span ID of %d bytes", len(spanID))
+	}
+	copy(config.TraceID[:], traceID)
+	copy(config.SpanID[:], spanID)
+	state, err := oteltrace.ParseTraceState(traceState)
+	if err != nil {
+		return oteltrace.SpanContext{}, err
+	}
+	config.TraceState = state
+	config.TraceFlags = oteltrace.TraceFlags(flags & uint32(tracepb.SpanFlags_SPAN_FLAGS_TRACE_FLAGS_MASK))
+	config.Remote = flags&uint32(tracepb.SpanFlags_SPAN_FLAGS_CONTEXT_IS_REMOTE_MASK) != 0
+	return oteltrace.NewSpanContext(config), nil
+}
+
+// encodeStatusCode converts a span status code to OTLP, whose values differ.
+func encodeStatusCode(code codes.Code) tracepb.Status_StatusCode {
+	switch code {
+	case codes.Ok:
+		return tracepb.Status_STATUS_CODE_OK
+	case codes.Error:
+		return tracepb.Status_STATUS_CODE_ERROR
+	default:
+		return tracepb.Status_STATUS_CODE_UNSET
+	}
+}
+
+// decodeStatusCode converts an OTLP status code back to a span status code.
+func decodeStatusCode(code tracepb.Status_StatusCode) codes.Code {
+	switch code {
+	case tracepb.Status_STATUS_CODE_OK:
+		return codes.Ok
+	case tracepb.Status_STATUS_CODE_ERROR:
+		return codes.Error
+	default:
+		return codes.Unset
+	}
+}
+
+// encodeTime converts a time to Unix nanoseconds, zero if it is unset.
+func encodeTime(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixNano())
+}
+
+// decodeTime converts Unix nanoseconds back to a time.
+func decodeTime(nanos uint64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(nanos))
+}
+
+// encodeResource converts a resource to OTLP. Its schema URL is set on the
+// enclosing message.
+func encodeResource(res *resource.Resource) *resourcepb.Resource {
+	return &resourcepb.Resource{Attributes: encodeAttributes(res.Attributes())}
+}
+
+// decodeResource converts an OTLP resource back to a resource.
+func decodeResource(res *resourcepb.Resource, schemaURL string) (*resource.Resource, error) {
+	attrs, err := decodeAttributes(res.GetAttributes())
+	if err != nil {
+		return nil, err
+	}
+	return resource.NewWithAttributes(schemaURL, attrs...), nil
+}
+
+// encodeScope converts an instrumentation scope to OTLP. Its schema URL is
+// set on the enclosing message.
+func encodeScope(scope instrumentation.Scope) *commonpb.InstrumentationScope {
+	return &commonpb.InstrumentationScope{
+		Name:       scope.Name,
+		Version:    scope.Version,
+		Attributes: encodeAttributes(scope.Attributes.ToSlice()),
+	}
+}
+
+// decodeScope converts an OTLP instrumentation scope back to a scope.
+func decodeScope(scope *commonpb.InstrumentationScope, schemaURL string) (instrumentation.Scope, error) {
+	attrs, err := decodeAttributes(scope.GetAttributes())
+	if err != nil {
+		return instrumentation.Scope{}, err
+	}
+	return instrumentation.Scope{
+		Name:       scope.GetName(),
+		Version:    scope.GetVersion(),
+		SchemaURL:  schemaURL,
+		Attributes: attribute.NewSet(attrs...),
+	}, nil
+}
+
+// encodeAttributes converts attributes to OTLP.
+func encodeAttributes(attrs []attribute.KeyValue) []*commonpb.KeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	encoded := make([]*commonpb.KeyValue, len(attrs))
+	for i, attr := range attrs {
+		encoded[i] = &commonpb.KeyValue{Key: string(attr.Key), Value: encodeValue(attr.Value)}
+	}
+	return encoded
+}
+
+// encodeValue converts an attribute value to OTLP. An invalid value becomes an
+// empty one.
+func encodeValue(value attribute.Value) *commonpb.AnyValue {
+	switch value.Type() {
+	case attribute.BOOL:
+		return boolValue(value.AsBool())
+	case attribute.INT64:
+		return intValue(value.AsInt64())
+	case attribute.FLOAT64:
+		return doubleValue(value.AsFloat64())
+	case attribute.STRING:
+		return stringValue(value.AsString())
+	case attribute.BOOLSLICE:
+		return arrayValue(value.AsBoolSlice(), boolValue)
+	case attribute.INT64SLICE:
+		return arrayValue(value.AsInt64Slice(), intValue)
+	case attribute.FLOAT64SLICE:
+		return arrayValue(value.AsFloat64Slice(), doubleValue)
+	case attribute.STRINGSLICE:
+		return arrayValue(value.AsStringSlice(), stringValue)
+	default:
+		return &commonpb.AnyValue{}
+	}
+}
+
+// boolValue returns a bool as an OTLP value.
+func boolValue(v bool) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v}}
+}
+
+// intValue returns an int64 as an OTLP value.
+func intValue(v int64) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v}}
+}
+
+// doubleValue returns a float64 as an OTLP value.
+func doubleValue(v float64) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v}}
+}
+
+// stringValue returns a string as an OTLP value.
+func stringValue(v string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}}
+}
+
+// arrayValue converts a slice attribute value to an OTLP array.
+func arrayValue[T any](values []T, encode func(T) *commonpb.AnyValue) *commonpb.AnyValue {
+	array := &commonpb.ArrayValue{Values: make([]*commonpb.AnyValue, len(values))}
+	for i, v := range values {
+		array.Values[i] = encode(v)
+	}
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: array}}
+}
+
+// decodeAttributes converts OTLP attributes back to attributes.
+func decodeAttributes(attrs []*commonpb.KeyValue) ([]attribute.KeyValue, error) {
+	if len(attrs) == 0 {
+		return nil, nil
+	}
+	decoded := make([]attribute.KeyValue, len(attrs))
+	for i, attr := range attrs {
+		value, err := decodeValue(attr.Value)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %q: %w", attr.Key, err)
+		}
+		decoded[i] = attribute.KeyValue{Key: attribute.Key(attr.Key), Value: value}
+	}
+	return decoded, nil
+}
+
+// decodeValue converts an OTLP value written by encodeValue back to an
+// attribute value.
+func decodeValue(value *commonpb.AnyValue) (attribute.Value, error) {
+	switch v := value.GetValue().(type) {
+	case nil:
+		return attribute.Value{}, nil
+	case *commonpb.AnyValue_BoolValue:
+		return attribute.BoolValue(v.BoolValue), nil
+	case *commonpb.AnyValue_IntValue:
+		return attribute.Int64Value(v.IntValue), nil
+	case *commonpb.AnyValue_DoubleValue:
+		return attribute.Float64Value(v.DoubleValue), nil
+	case *commonpb.AnyValue_StringValue:
+		return attribute.StringValue(v.StringValue), nil
+	case *commonpb.AnyValue_ArrayValue:
+		return decodeArray(v.ArrayValue.GetValues())
+	default:
+		return attribute.Value{}, fmt.Errorf("unsupported value type %T", v)
+	}
+}
+
+// decodeArray converts an OTLP array back to a slice attribute value. OTLP
+// arrays are untyped, so empty ones become empty string slices.
+func decodeArray(values []*commonpb.AnyValue) (attribute.Value, error) {
+	if len(values) == 0 {
+		return attribute.StringSliceValue(nil), nil
+	}
+	switch values[0].GetValue().(type) {
+	case *commonpb.AnyValue_BoolValue:
+		decoded, err := decodeElements(values, func(v *commonpb.AnyValue_BoolValue) bool { return v.BoolValue })
+		return attribute.BoolSliceValue(decoded), err
+	case *commonpb.AnyValue_IntValue:
+		decoded, err := decodeElements(values, func(v *commonpb.AnyValue_IntValue) int64 { return v.IntValue })
+		return attribute.Int64SliceValue(decoded), err
+	case *commonpb.AnyValue_DoubleValue:
+		decoded, err := decodeElements(values, func(v *commonpb.AnyValue_DoubleValue) float64 { return v.DoubleValue })
+		return attribute.Float64SliceValue(decoded), err
+	case *commonpb.AnyValue_StringValue:
+		decoded, err := decodeElements(values, func(v *commonpb.AnyValue_StringValue) string { return v.StringValue })
+		return attribute.StringSliceValue(decoded), err
+	default:
+		return attribute.Value{}, fmt.Errorf("unsupported array element type %T", values[0].GetValue())
+	}
+}
+
+// decodeElements returns the elements of an OTLP array, which must all be of
+// the value type V.
+func decodeElements[V any, T any](values []*commonpb.AnyValue, get func(V) T) ([]T, error) {
+	decoded := make([]T, len(values))
+	for i, value := range values {
+		v, ok := value.GetValue().(V)
+		if !ok {
+			return nil, fmt.Errorf("array mixes %T and %T elements", values[0].GetValue(), value.GetValue())
+		}
+		decoded[i] = get(v)
+	}
+	return decoded, nil
+}
+
+// encodeMetrics serializes a metrics export.
+func encodeMetrics(rm *metricdata.ResourceMetrics) ([]byte, error) {
+	resourceMetrics := &metricpb.ResourceMetrics{Resource: encodeResource(rm.Resource), SchemaUrl: rm.Resource.SchemaURL()}
+	for _, sm := range rm.ScopeMetrics {
+		scopeMetrics := &metricpb.ScopeMetrics{Scope: encodeScope(sm.Scope), SchemaUrl: sm.Scope.SchemaURL}
+		for _, m := range sm.Metrics {
+			metric, err := encodeMetric(m)
+			if err != nil {
+				return nil, err
+			}
+			scopeMetrics.Metrics = append(scopeMetrics.Metrics, metric)
+		}
+		resourceMetrics.ScopeMetrics = append(resourceMetrics.ScopeMetrics, scopeMetrics)
+	}
+	return proto.Marshal(&metricpb.MetricsData{ResourceMetrics: []*metricpb.ResourceMetrics{resourceMetrics}})
+}
+
+// encodeMetric converts a metric to OTLP.
+func encodeMetric(m metricdata.Metrics) (*metricpb.Metric, error) {
+	metric := &metricpb.Metric{Name: m.Name, Description: m.Description, Unit: m.Unit}
+	switch data := m.Data.(type) {
+	case metricdata.Gauge[int64]:
+		metric.Data = &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{DataPoints: encodeNumberPoints(data.DataPoints)}}
+	case metricdata.Gauge[float64]:
+		metric.Data = &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{DataPoints: encodeNumberPoints(data.DataPoints)}}
+	case metricdata.Sum[int64]:
+		metric.Data = encodeSum(data)
+	case metricdata.Sum[float64]:
+		metric.Data = encodeSum(data)
+	case metricdata.Histogram[int64]:
+		metric.Data = encodeHistogram(data)
+	case metricdata.Histogram[float64]:
+		metric.Data = encodeHistogram(data)
+	case metricdata.ExponentialHistogram[int64]:
+		metric.Data = encodeExponentialHistogram(data)
+	case metricdata.ExponentialHistogram[float64]:
+		metric.Data = encodeExponentialHistogram(data)
+	case metricdata.Summary:
+		metric.Data = encodeSummary(data)
+	default:
+		return nil, fmt.Errorf("metric %q has unsupported data %T", m.Name, m.Data)
+	}
+
+	switch m.Data.(type) {
+	case metricdata.Gauge[int64], metricdata.Sum[int64], metricdata.Histogram[int64], metricdata.ExponentialHistogram[int64]:
+		metric.Metadata = []*commonpb.KeyValue{{Key: valueTypeMetadataKey, Value: stringValue("int64")}}
+	}
+	return metric, nil
+}
+
+// decodeMetrics restores a metrics export serialized by encodeMetrics.
+func decodeMetrics(data []byte) (*metricdata.ResourceMetrics, error) {
+	var metrics metricpb.MetricsData
+	if err := proto.Unmarshal(data, &metrics); err != nil {
+		return nil, err
+	}
+	if len(metrics.ResourceMetrics) != 1 {
+		return nil, fmt.Errorf("expected metrics of one resource, got %d", len(metrics.ResourceMetrics))
+	}
+
+	resourceMetrics := metrics.ResourceMetrics[0]
+	res, err := decodeResource(resourceMetrics.Resource, resourceMetrics.SchemaUrl)
+	if err != nil {
+		return nil, err
+	}
+	rm := &metricdata.ResourceMetrics{Resource: res}
+	for _, scopeMetrics := range resourceMetrics.ScopeMetrics {
+		scope, err := decodeScope(scopeMetrics.Scope, scopeMetrics.SchemaUrl)
+		if err != nil {
+			return nil, err
+		}
+		sm := metricdata.ScopeMetrics{Scope: scope}
+		for _, m := range scopeMetrics.Metrics {
+			metric, err := decodeMetric(m)
+			if err != nil {
+				return nil, fmt.Errorf("metric %q: %w", m.Name, err)
+			}
+			sm.Metrics = append(sm.Metrics, metric)
+		}
+		rm.ScopeMetrics = append(rm.ScopeMetrics, sm)
+	}
+	return rm, nil
+}
+
+// decodeMetric converts an OTLP metric back to a metric.
+func decodeMetric(m *metricpb.Metric) (metricdata.Metrics, error) {
+	isInt := false
+	for _, kv := range m.Metadata {
+		if kv.Key == valueTypeMetadataKey {
+			isInt = kv.Value.GetStringValue() == "int64"
+		}
+	}
+
+	metric := metricdata.Metrics{Name: m.Name, Description: m.Description, Unit: m.Unit}
+	var err error
+	switch data := m.Data.(type) {
+	case *metricpb.Metric_Gauge:
+		if isInt {
+			metric.Data, err = decodeGauge[int64](data.Gauge)
+		} else {
+			metric.Data, err = decodeGauge[float64](data.Gauge)
+		}
+	case *metricpb.Metric_Sum:
+		if isInt {
+			metric.Data, err = decodeSum[int64](data.Sum)
+		} else {
+			metric.Data, err = decodeSum[float64](data.Sum)
+		}
+	case *metricpb.Metric_Histogram:
+		if isInt {
+			metric.Data, err = decodeHistogram[int64](data.Histogram)
+		} else {
+			metric.Data, err = decodeHistogram[float64](data.Histogram)
+		}
+	case *metricpb.Metric_ExponentialHistogram:
+		if isInt {
+			metric.Data, err = decodeExponentialHistogram[int64](data.ExponentialHistogram)
+		} else {
+			metric.Data, err = decodeExponentialHistogram[float64](data.ExponentialHistogram)
+		}
+	case *metricpb.Metric_Summary:
+		metric.Data, err = decodeSummary(data.Summary)
+	default:
+		err = fmt.Errorf("unsupported data %T", m.Data)
+	}
+	return metric, err
+}
+
+// encodeNumberPoints converts gauge or sum data points to OTLP, keeping int64
+// values as integers.
+func encodeNumberPoints[N int64 | float64](points []metricdata.DataPoint[N]) []*metricpb.NumberDataPoint {
+	encoded := make([]*metricpb.NumberDataPoint, len(points))
+	for i, p := range points {
+		point := &metricpb.NumberDataPoint{
+			Attributes:        encodeAttributes(p.Attributes.ToSlice()),
+			StartTimeUnixNano: encodeTime(p.StartTime),
+			TimeUnixNano:      encodeTime(p.Time),
+			Exemplars:         encodeExemplars(p.Exemplars),
+		}
+		switch v := any(p.Value).(type) {
+		case int64:
+			point.Value = &metricpb.NumberDataPoint_AsInt{AsInt: v}
+		case float64:
+			point.Value = &metricpb.NumberDataPoint_AsDouble{AsDouble: v}
+		}
+		encoded[i] = point
+	}
+	return encoded
+}
+
+// decodeNumberPoints converts OTLP gauge or sum data points back to data
+// points.
+func decodeNumberPoints[N int64 | float64](points []*metricpb.NumberDataPoint) ([]metricdata.DataPoint[N], error) {
+	decoded := make([]metricdata.DataPoint[N], len(points))
+	for i, p := range points {
+		attrs, err := decodeAttributes(p.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		exemplars, err := decodeExemplars[N](p.Exemplars)
+		if err != nil {
+			return nil, err
+		}
+		decoded[i] = metricdata.DataPoint[N]{
+			Attributes: attribute.NewSet(attrs...),
+			StartTime:  decodeTime(p.StartTimeUnixNano),
+			Time:       decodeTime(p.TimeUnixNano),
+			Exemplars:  exemplars,
+		}
+		switch v := p.Value.(type) {
+		case *metricpb.NumberDataPoint_AsInt:
+			decoded[i].Value = N(v.AsInt)
+		case *metricpb.NumberDataPoint_AsDouble:
+			decoded[i].Value = N(v.AsDouble)
+		}
+	}
+	return decoded, nil
+}
+
+// decodeGauge converts OTLP gauge data back to a gauge.
+func decodeGauge[N int64 | float64](gauge *metricpb.Gauge) (metricdata.Aggregation, error) {
+	points, err := decodeNumberPoints[N](gauge.GetDataPoints())
+	if err != nil {
+		return nil, err
+	}
+	return metricdata.Gauge[N]{DataPoints: points}, nil
+}
+
+// encodeSum converts sum data to OTLP.
+func encodeSum[N int64 | float64](data metricdata.Sum[N]) *metricpb.Metric_Sum {
+	return &metricpb.Metric_Sum{Sum: &metricpb.Sum{
+		DataPoints:             encodeNumberPoints(data.DataPoints),
+		AggregationTemporality: encodeTemporality(data.Temporality),
+		IsMonotonic:            data.IsMonotonic,
+	}}
+}
+
+// decodeSum converts OTLP sum data back to a sum.
+func decodeSum[N int64 | float64](sum *metricpb.Sum) (metricdata.Aggregation, error) {
+	points, err := decodeNumberPoints[N](sum.GetDataPoints())
+	if err != nil {
+		return nil, err
+	}
+	return metricdata.Sum[N]{
+		DataPoints:  points,
+		Temporality: decodeTemporality(sum.GetAggregationTemporality()),
+		IsMonotonic: sum.GetIsMonotonic(),
+	}, nil
+}
+
+// encodeHistogram converts histogram data to OTLP. Sums and extrema are
+// doubles in OTLP, as in a direct OTLP export.
+func encodeHistogram[N int64 | float64](data metricdata.Histogram[N]) *metricpb.Metric_Histogram {
+	histogram := &metricpb.Histogram{AggregationTemporality: encodeTemporality(data.Temporality)}
+	for _, p := range data.DataPoints {
+		sum := float64(p.Sum)
+		histogram.DataPoints = append(histogram.DataPoints, &metricpb.HistogramDataPoint{
+			Attributes:        encodeAttributes(p.Attributes.ToSlice()),
+			StartTimeUnixNano: encodeTime(p.StartTime),
+			TimeUnixNano:      encodeTime(p.Time),
+			Count:             p.Count,
+			Sum:               &sum,
+			BucketCounts:      p.BucketCounts,
+			ExplicitBounds:    p.Bounds,
+			Exemplars:         encodeExemplars(p.Exemplars),
+			Min:               encodeExtrema(p.Min),
+			Max:               encodeExtrema(p.Max),
+		})
+	}
+	return &metricpb.Metric_Histogram{Histogram: histogram}
+}
+
+// decodeHistogram converts OTLP histogram data back to a histogram.
+func decodeHistogram[N int64 | float64](histogram *metricpb.Histogram) (metricdata.Aggregation, error) {
+	data := metricdata.Histogram[N]{Temporality: decodeTemporality(histogram.GetAggregationTemporality())}
+	for _, p := range histogram.GetDataPoints() {
+		attrs, err := decodeAttributes(p.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		exemplars, err := decodeExemplars[N](p.Exemplars)
+		if err != nil {
+			return nil, err
+		}
+		data.DataPoints = append(data.DataPoints, metricdata.HistogramDataPoint[N]{
+			Attributes:   attribute.NewSet(attrs...),
+			StartTime:    decodeTime(p.StartTimeUnixNano),
+			Time:         decodeTime(p.TimeUnixNano),
+			Count:        p.Count,
+			Bounds:       p.ExplicitBounds,
+			BucketCounts: p.BucketCounts,
+			Min:          decodeExtrema[N](p.Min),
+			Max:          decodeExtrema[N](p.Max),
+			Sum:          N(p.GetSum()),
+			Exemplars:    exemplars,
+		})
+	}
+	return data, nil
+}
+
+// encodeExponentialHistogram converts exponential histogram data to OTLP.
+func encodeExponentialHistogram[N int64 | float64](data metricdata.ExponentialHistogram[N]) *metricpb.Metric_ExponentialHistogram {
+	histogram := &metricpb.ExponentialHistogram{AggregationTemporality: encodeTemporality(data.Temporality)}
+	for _, p := range data.DataPoints {
+		sum := float64(p.Sum)
+		histogram.DataPoints = append(histogram.DataPoints, &metricpb.ExponentialHistogramDataPoint{
+			Attributes:        encodeAttributes(p.Attributes.ToSlice()),
+			StartTimeUnixNano: encodeTime(p.StartTime),
+			TimeUnixNano:      encodeTime(p.Time),
+			Count:             p.Count,
+			Sum:               &sum,
+			Scale:             p.Scale,
+			ZeroCount:         p.ZeroCount,
+			Positive:          &metricpb.ExponentialHistogramDataPoint_Buckets{Offset: p.PositiveBucket.Offset, BucketCounts: p.PositiveBucket.Counts},
+			Negative:          &metricpb.ExponentialHistogramDataPoint_Buckets{Offset: p.NegativeBucket.Offset, BucketCounts: p.NegativeBucket.Counts},
+			Exemplars:         encodeExemplars(p.Exemplars),
+			Min:               encodeExtrema(p.Min),
+			Max:               encodeExtrema(p.Max),
+			ZeroThreshold:     p.ZeroThreshold,
+		})
+	}
+	return &metricpb.Metric_ExponentialHistogram{ExponentialHistogram: histogram}
+}
+
+// decodeExponentialHistogram converts OTLP exponential histogram data back to
+// an exponential histogram.
+func decodeExponentialHistogram[N int64 | float64](histogram *metricpb.ExponentialHistogram) (metricdata.Aggregation, error) {
+	data := metricdata.ExponentialHistogram[N]{Temporality: decodeTemporality(histogram.GetAggregationTemporality())}
+	for _, p := range histogram.GetDataPoints() {
+		attrs, err := decodeAttributes(p.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		exemplars, err := decodeExemplars[N](p.Exemplars)
+		if err != nil {
+			return nil, err
+		}
+		data.DataPoints = append(data.DataPoints, metricdata.ExponentialHistogramDataPoint[N]{
+			Attributes:     attribute.NewSet(attrs...),
+			StartTime:      decodeTime(p.StartTimeUnixNano),
+			Time:           decodeTime(p.TimeUnixNano),
+			Count:          p.Count,
+			Min:            decodeExtrema[N](p.Min),
+			Max:            decodeExtrema[N](p.Max),
+			Sum:            N(p.GetSum()),
+			Scale:          p.Scale,
+			ZeroCount:      p.ZeroCount,
+			ZeroThreshold:  p.ZeroThreshold,
+			PositiveBucket: metricdata.ExponentialBucket{Offset: p.Positive.GetOffset(), Counts: p.Positive.GetBucketCounts()},
+			NegativeBucket: metricdata.ExponentialBucket{Offset: p.Negative.GetOffset(), Counts: p.Negative.GetBucketCounts()},
+			Exemplars:      exemplars,
+		})
+	}
+	return data, nil
+}
+
+// encodeSummary converts summary data to OTLP.
+func encodeSummary(data metricdata.Summary) *metricpb.Metric_Summary {
+	summary := &metricpb.Summary{}
+	for _, p := range data.DataPoints {
+		point := &metricpb.SummaryDataPoint{
+			Attributes:        encodeAttributes(p.Attributes.ToSlice()),
+			StartTimeUnixNano: encodeTime(p.StartTime),
+			TimeUnixNano:      encodeTime(p.Time),
+			Count:             p.Count,
+			Sum:               p.Sum,
+		}
+		for _, q := range p.QuantileValues {
+			point.QuantileValues = append(point.QuantileValues, &metricpb.SummaryDataPoint_ValueAtQuantile{Quantile: q.Quantile, Value: q.Value})
+		}
+		summary.DataPoints = append(summary.DataPoints, point)
+	}
+	return &metricpb.Metric_Summary{Summary: summary}
+}
+
+// decodeSummary converts OTLP summary data back to a summary.
+func decodeSummary(summary *metricpb.Summary) (metricdata.Aggregation, error) {
+	var data metricdata.Summary
+	for _, p := range summary.GetDataPoints() {
+		attrs, err := decodeAttributes(p.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		point := metricdata.SummaryDataPoint{
+			Attributes: attribute.NewSet(attrs...),
+			StartTime:  decodeTime(p.StartTimeUnixNano),
+			Time:       decodeTime(p.TimeUnixNano),
+			Count:      p.Count,
+			Sum:        p.Sum,
+		}
+		for _, q := range p.QuantileValues {
+			point.QuantileValues = append(point.QuantileValues, metricdata.QuantileValue{Quantile: q.Quantile, Value: q.Value})
+		}
+		data.DataPoints = append(data.DataPoints, point)
+	}
+	return data, nil
+}
+
+// encodeExemplars converts exemplars to OTLP.
+func encodeExemplars[N int64 | float64](exemplars []metricdata.Exemplar[N]) []*metricpb.Exemplar {
+	if len(exemplars) == 0 {
+		return nil
+	}
+	encoded := make([]*metricpb.Exemplar, len(exemplars))
+	for i, e := range exemplars {
+		exemplar := &metricpb.Exemplar{
+			FilteredAttributes: encodeAttributes(e.FilteredAttributes),
+			TimeUnixNano:       encodeTime(e.Time),
+			SpanId:             e.SpanID,
+			TraceId:            e.TraceID,
+		}
+		switch v := any(e.Value).(type) {
+		case int64:
+			exemplar.Value = &metricpb.Exemplar_AsInt{AsInt: v}
+		case float64:
+			exemplar.Value = &metricpb.Exemplar_AsDouble{AsDouble: v}
+		}
+		encoded[i] = exemplar
+	}
+	return encoded
+}
+
+// decodeExemplars converts OTLP exemplars back to exemplars.
+func decodeExemplars[N int64 | float64](exemplars []*metricpb.Exemplar) ([]metricdata.Exemplar[N], error) {
+	if len(exemplars) == 0 {
+		return nil, nil
+	}
+	decoded := make([]metricdata.Exemplar[N], len(exemplars))
+	for i, e := range exemplars {
+		attrs, err := decodeAttributes(e.FilteredAttributes)
+		if err != nil {
+			return nil, err
+		}
+		decoded[i] = metricdata.Exemplar[N]{
+			FilteredAttributes: attrs,
+			Time:               decodeTime(e.TimeUnixNano),
+			SpanID:             e.SpanId,
+			TraceID:            e.TraceId,
+		}
+		switch v := e.Value.(type) {
+		case *metricpb.Exemplar_AsInt:
+			decoded[i].Value = N(v.AsInt)
+		case *metricpb.Exemplar_AsDouble:
+			decoded[i].Value = N(v.AsDouble)
+		}
+	}
+	return decoded, nil
+}
+
+// encodeTemporality converts a temporality to OTLP, whose values differ.
+func encodeTemporality(temporality metricdata.Temporality) metricpb.AggregationTemporality {
+	switch temporality {
+	case metricdata.DeltaTemporality:
+		return metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA
+	case metricdata.CumulativeTemporality:
+		return metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+	default:
+		return metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_UNSPECIFIED
+	}
+}
+
+// decodeTemporality converts an OTLP temporality back to a temporality.
+func decodeTemporality(temporality metricpb.AggregationTemporality) metricdata.Temporality {
+	switch temporality {
+	case metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA:
+		return metricdata.DeltaTemporality
+	case metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE:
+		return metricdata.CumulativeTemporality
+	default:
+		return 0
+	}
+}
+
+// encodeExtrema returns the value of a histogram min or max, or nil if unset.
+func encodeExtrema[N int64 | float64](e metricdata.Extrema[N]) *float64 {
+	v, ok := e.Value()
+	if !ok {
+		return nil
+	}
+	f := float64(v)
+	return &f
+}
+
+// decodeExtrema converts an OTLP min or max back to an extrema.
+func decodeExtrema[N int64 | float64](v *float64) metricdata.Extrema[N] {
+	if v == nil {
+		return metricdata.Extrema[N]{}
+	}
+	return metricdata.NewExtrema(N(*v))
+}
@@ -0,0 +1,299 @@
+package opentelemetry
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata/metricdatatest"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// codecAttributes covers every attribute type.
+var codecAttributes = []attribute.KeyValue{
+	attribute.Bool("bool", true),
+	attribute.Int64("int", math.MaxInt64),
+	attribute.Float64("float", 1.5),
+	attribute.String("string", "value"),
+	attribute.String("empty", ""),
+	attribute.BoolSlice("bools", []bool{true, false}),
+	attribute.Int64Slice("ints", []int64{math.MinInt64, 0, 1}),
+	attribute.Float64Slice("floats", []float64{0.5, -2}),
+	attribute.StringSlice("strings", []string{"a", "b"}),
+}
+
+// testSpanContext returns a span context with the given IDs.
+func testSpanContext(t *testing.T, traceID, spanID byte, remote bool) oteltrace.SpanContext {
+	t.Helper()
+	state, err := oteltrace.ParseTraceState("vendor=value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    oteltrace.TraceID{traceID, 1},
+		SpanID:     oteltrace.SpanID{spanID, 1},
+		TraceFlags: oteltrace.FlagsSampled,
+		TraceState: state,
+		Remote:     remote,
+	})
+}
+
+func TestSpanCodecRoundTrip(t *testing.T) {
+	start := time.Unix(1700000000, 123456789)
+	service := resource.NewWithAttributes("https://opentelemetry.io/schemas/1.26.0", attribute.String("service.name", "test"))
+	other := resource.NewSchemaless(attribute.String("service.name", "other"))
+	scope := instrumentation.Scope{
+		Name:       "genkit",
+		Version:    "1.2.0",
+		SchemaURL:  "https://opentelemetry.io/schemas/1.26.0",
+		Attributes: attribute.NewSet(attribute.String("scope", "attr")),
+	}
+
+	parent := testSpanContext(t, 1, 1, true)
+	stubs := tracetest.SpanStubs{
+		{
+			Name:        "root",
+			SpanContext: testSpanContext(t, 1, 2, false),
+			Parent:      oteltrace.NewSpanContext(oteltrace.SpanContextConfig{TraceID: parent.TraceID(), SpanID: parent.SpanID(), TraceFlags: oteltrace.FlagsSampled, Remote: true}),
+			SpanKind:    oteltrace.SpanKindServer,
+			StartTime:   start,
+			EndTime:     start.Add(time.Second),
+			Attributes:  codecAttributes,
+			Events: []trace.Event{{
+				Name:                  "exception",
+				Attributes:            []attribute.KeyValue{attribute.String("exception.type", "boom")},
+				DroppedAttributeCount: 1,
+				Time:                  start.Add(time.Millisecond),
+			}},
+			Links: []trace.Link{{
+				SpanContext:           testSpanContext(t, 2, 3, true),
+				Attributes:            []attribute.KeyValue{attribute.Int("turn", 1)},
+				DroppedAttributeCount: 2,
+			}},
+			Status:               trace.Status{Code: codes.Error, Description: "failed"},
+			DroppedAttributes:    3,
+			DroppedEvents:        4,
+			DroppedLinks:         5,
+			Resource:             service,
+			InstrumentationScope: scope,
+		},
+		{
+			Name:                 "child",
+			SpanContext:          testSpanContext(t, 1, 4, false),
+			Parent:               oteltrace.NewSpanContext(oteltrace.SpanContextConfig{TraceID: parent.TraceID(), SpanID: oteltrace.SpanID{2, 1}, TraceFlags: oteltrace.FlagsSampled}),
+			SpanKind:             oteltrace.SpanKindInternal,
+			StartTime:            start,
+			EndTime:              start.Add(time.Millisecond),
+			Status:               trace.Status{Code: codes.Ok},
+			Resource:             service,
+			InstrumentationScope: instrumentation.Scope{Name: "other"},
+		},
+		{
+			Name:        "other resource",
+			SpanContext: testSpanContext(t, 3, 5, false),
+			StartTime:   start,
+			Resource:    other,
+		},
+	}
+
+	data, err := encodeSpans(stubs.Snapshots())
+	if err != nil {
+		t.Fatalf("encodeSpans() error = %v", err)
+	}
+	decoded, err := decodeSpans(data)
+	if err != nil {
+		t.Fatalf("decodeSpans() error = %v", err)
+	}
+	if len(decoded) != len(stubs) {
+		t.Fatalf("decodeSpans() returned %d spans, want %d", len(decoded), len(stubs))
+	}
+
+	for i, span := range decoded {
+		want := stubs[i]
+		got := tracetest.SpanStubFromReadOnlySpan(span)
+		if !got.Resource.Equal(want.Resource) || got.Resource.SchemaURL() != want.Resource.SchemaURL() {
+			t.Errorf("%s resource = %v, want %v", want.Name, got.Resource, want.Resource)
+		}
+		if !got.InstrumentationScope.Attributes.Equals(&want.InstrumentationScope.Attributes) {
+			t.Errorf("%s scope attributes = %v, want %v", want.Name, got.InstrumentationScope.Attributes, want.InstrumentationScope.Attributes)
+		}
+		got.Resource, want.Resource = nil, nil
+		got.InstrumentationScope.Attributes, want.InstrumentationScope.Attributes = attribute.Set{}, attribute.Set{}
+		got.InstrumentationLibrary = want.InstrumentationLibrary
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("decoded span:\n%+v\nwant:\n%+v", got, want)
+		}
+	}
+}
+
+func TestSpanCodecRejectsInvalidData(t *testing.T) {
+	if _, err := decodeSpans([]byte("not protobuf")); err == nil {
+		t.Error("decodeSpans() of invalid data succeeded")
+	}
+}
+
+func TestMetricCodecRoundTrip(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	now := start.Add(time.Minute)
+	attrs := attribute.NewSet(attribute.String("modelName", "googleai/gemini"), attribute.Int("turn", 2))
+	exemplar := metricdata.Exemplar[float64]{
+		FilteredAttributes: []attribute.KeyValue{attribute.String("user", "u1")},
+		Time:               now,
+		Value:              12.5,
+		SpanID:             []byte{1, 2, 3, 4, 5, 6, 7, 8},
+		TraceID:            []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+	}
+
+	rm := &metricdata.ResourceMetrics{
+		Resource: resource.NewWithAttributes("https://opentelemetry.io/schemas/1.26.0", attribute.String("service.name", "test")),
+		ScopeMetrics: []metricdata.ScopeMetrics{{
+			Scope: instrumentation.Scope{Name: "genkit", Version: "1.2.0", Attributes: attribute.NewSet(attribute.String("scope", "attr"))},
+			Metrics: []metricdata.Metrics{
+				{
+					Name:        "genkit/ai/generate/input/tokens",
+					Description: "Counts input tokens to a Genkit model.",
+					Unit:        "1",
+					Data: metricdata.Sum[int64]{
+						Temporality: metricdata.DeltaTemporality,
+						IsMonotonic: true,
+						DataPoints:  []metricdata.DataPoint[int64]{{Attributes: attrs, StartTime: start, Time: now, Value: math.MaxInt64}},
+					},
+				},
+				{
+					Name: "genkit/ai/generate/cost",
+					Data: metricdata.Sum[float64]{
+						Temporality: metricdata.CumulativeTemporality,
+						DataPoints:  []metricdata.DataPoint[float64]{{Attributes: attrs, StartTime: start, Time: now, Value: math.Inf(1)}},
+					},
+				},
+				{
+					Name: "genkit.otel.spans.queue.size",
+					Data: metricdata.Gauge[int64]{
+						DataPoints: []metricdata.DataPoint[int64]{{Time: now, Value: -3}},
+					},
+				},
+				{
+					Name: "genkit.otel.export.fill",
+					Data: metricdata.Gauge[float64]{
+						DataPoints: []metricdata.DataPoint[float64]{{Time: now, Value: 0.25}},
+					},
+				},
+				{
+					Name: "genkit/ai/generate/latency",
+					Unit: "ms",
+					Data: metricdata.Histogram[int64]{
+						Temporality: metricdata.DeltaTemporality,
+						DataPoints: []metricdata.HistogramDataPoint[int64]{{
+							Attributes:   attrs,
+							StartTime:    start,
+							Time:         now,
+							Count:        3,
+							Bounds:       []float64{10, 100},
+							BucketCounts: []uint64{1, 1, 1},
+							Min:          metricdata.NewExtrema[int64](5),
+							Max:          metricdata.NewExtrema[int64](500),
+							Sum:          555,
+						}},
+					},
+				},
+				{
+					Name: "genkit/feature/latency",
+					Data: metricdata.Histogram[float64]{
+						Temporality: metricdata.CumulativeTemporality,
+						DataPoints: []metricdata.HistogramDataPoint[float64]{{
+							StartTime:    start,
+							Time:         now,
+							Count:        1,
+							Bounds:       []float64{1},
+							BucketCounts: []uint64{0, 1},
+							Sum:          12.5,
+							Exemplars:    []metricdata.Exemplar[float64]{exemplar},
+						}},
+					},
+				},
+				{
+					Name: "rpc.server.duration",
+					Data: metricdata.ExponentialHistogram[float64]{
+						Temporality: metricdata.CumulativeTemporality,
+						DataPoints: []metricdata.ExponentialHistogramDataPoint[float64]{{
+							Attributes:     attrs,
+							StartTime:      start,
+							Time:           now,
+							Count:          4,
+							Min:            metricdata.NewExtrema(0.5),
+							Max:            metricdata.NewExtrema(8.0),
+							Sum:            11,
+							Scale:          2,
+							ZeroCount:      1,
+							PositiveBucket: metricdata.ExponentialBucket{Offset: -1, Counts: []uint64{1, 2}},
+							NegativeBucket: metricdata.ExponentialBucket{Offset: 3, Counts: []uint64{}},
+							ZeroThreshold:  0.001,
+						}},
+					},
+				},
+				{
+					Name: "legacy.summary",
+					Data: metricdata.Summary{
+						DataPoints: []metricdata.SummaryDataPoint{{
+							Attributes: attrs,
+							StartTime:  start,
+							Time:       now,
+							Count:      2,
+							Sum:        3,
+							QuantileValues: []metricdata.QuantileValue{
+								{Quantile: 0.5, Value: 1},
+								{Quantile: 0.99, Value: 2},
+							},
+						}},
+					},
+				},
+			},
+		}},
+	}
+
+	data, err := encodeMetrics(rm)
+	if err != nil {
+		t.Fatalf("encodeMetrics() error = %v", err)
+	}
+	decoded, err := decodeMetrics(data)
+	if err != nil {
+		t.Fatalf("decodeMetrics() error = %v", err)
+	}
+	if !decoded.Resource.Equal(rm.Resource) || decoded.Resource.SchemaURL() != rm.Resource.SchemaURL() {
+		t.Errorf("resource = %v, want %v", decoded.Resource, rm.Resource)
+	}
+	if len(decoded.ScopeMetrics) != 1 {
+		t.Fatalf("decodeMetrics() returned %d scopes, want 1", len(decoded.ScopeMetrics))
+	}
+	metricdatatest.AssertEqual(t, rm.ScopeMetrics[0], decoded.ScopeMetrics[0])
+}
+
+func TestMetricCodecNaN(t *testing.T) {
+	rm := &metricdata.ResourceMetrics{
+		Resource: resource.Empty(),
+		ScopeMetrics: []metricdata.ScopeMetrics{{Metrics: []metricdata.Metrics{{
+			Name: "gauge",
+			Data: metricdata.Gauge[float64]{DataPoints: []metricdata.DataPoint[float64]{{Value: math.NaN()}}},
+		}}}},
+	}
+	data, err := encodeMetrics(rm)
+	if err != nil {
+		t.Fatalf("encodeMetrics() error = %v", err)
+	}
+	decoded, err := decodeMetrics(data)
+	if err != nil {
+		t.Fatalf("decodeMetrics() error = %v", err)
+	}
+	gauge, ok := decoded.ScopeMetrics[0].Metrics[0].Data.(metricdata.Gauge[float64])
+	if !ok || len(gauge.DataPoints) != 1 || !math.IsNaN(gauge.DataPoints[0].Value) {
+		t.Errorf("decoded gauge = %+v, want a NaN point", decoded.ScopeMetrics[0].Metrics[0].Data)
+	}
+}
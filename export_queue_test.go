@@ -0,0 +1,250 @@
+package opentelemetry
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// flakyEndpoint records the batches it receives while up.
+type flakyEndpoint struct {
+	mu       sync.Mutex
+	down     bool
+	attempts int
+	received []string
+}
+
+// export receives a batch, or fails while the endpoint is down.
+func (e *flakyEndpoint) export(_ context.Context, batch string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.attempts++
+	if e.down {
+		return errors.New("endpoint unavailable")
+	}
+	e.received = append(e.received, batch)
+	return nil
+}
+
+// setDown takes the endpoint down or brings it back up.
+func (e *flakyEndpoint) setDown(down bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.down = down
+}
+
+// state returns the number of export attempts and the received batches.
+func (e *flakyEndpoint) state() (int, []string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.attempts, slices.Clone(e.received)
+}
+
+// newStringQueue opens a queue of string batches in dir.
+func newStringQueue(t *testing.T, dir string, maxBytes int64, endpoint *flakyEndpoint) *queuedExport[string] {
+	t.Helper()
+	q, err := newQueuedExport("test", dir, maxBytes,
+		func(batch string) ([]byte, error) { return []byte(batch), nil },
+		func(data []byte) (string, error) { return string(data), nil },
+		endpoint.export)
+	if err != nil {
+		t.Fatalf("newQueuedExport() error = %v", err)
+	}
+	t.Cleanup(q.close)
+	return q
+}
+
+// sendAll sends each batch and fails the test if one cannot be spooled.
+func sendAll(t *testing.T, q *queuedExport[string], batches ...string) {
+	t.Helper()
+	for _, batch := range batches {
+		if err := q.send(context.Background(), batch); err != nil {
+			t.Fatalf("send(%q) error = %v", batch, err)
+		}
+	}
+}
+
+// endBackoff lets the next send retry the endpoint at once.
+func endBackoff(q *queuedExport[string]) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.retryAt = time.Time{}
+}
+
+// waitForDrain waits until the background replay has emptied the queue.
+func waitForDrain(t *testing.T, q *queuedExport[string]) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for q.spooled() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d batches still spooled", q.spooled())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestQueuedExportSendsDirectlyWhileUp(t *testing.T) {
+	endpoint := &flakyEndpoint{}
+	q := newStringQueue(t, t.TempDir(), 1<<20, endpoint)
+
+	sendAll(t, q, "a", "b")
+	if attempts, received := endpoint.state(); attempts != 2 || !slices.Equal(received, []string{"a", "b"}) {
+		t.Errorf("endpoint got %d attempts and %v, want 2 and [a b]", attempts, received)
+	}
+	if got := q.spooled(); got != 0 {
+		t.Errorf("spooled() = %d, want 0", got)
+	}
+}
+
+func TestQueuedExportBacksOffDuringOutage(t *testing.T) {
+	endpoint := &flakyEndpoint{down: true}
+	q := newStringQueue(t, t.TempDir(), 1<<20, endpoint)
+
+	// Only the first batch reaches the endpoint; the rest wait for the backoff
+	sendAll(t, q, "a", "b", "c")
+	if attempts, _ := endpoint.state(); attempts != 1 {
+		t.Errorf("endpoint got %d attempts during the backoff, want 1", attempts)
+	}
+	if got := q.spooled(); got != 3 {
+		t.Fatalf("spooled() = %d, want 3", got)
+	}
+
+	// After the backoff, a send retries only the oldest batch
+	endBackoff(q)
+	sendAll(t, q, "d")
+	if attempts, _ := endpoint.state(); attempts != 2 {
+		t.Errorf("endpoint got %d attempts, want 2 with only the front retried", attempts)
+	}
+	q.mu.Lock()
+	failures, backoff := q.failures, time.Until(q.retryAt)
+	q.mu.Unlock()
+	if failures != 2 || backoff <= exportQueueMinBackoff || backoff > 2*exportQueueMinBackoff {
+		t.Errorf("after %d failures the backoff is %v, want the minimum doubled", failures, backoff)
+	}
+}
+
+func TestQueuedExportReplaysInOrderAfterOutage(t *testing.T) {
+	endpoint := &flakyEndpoint{down: true}
+	q := newStringQueue(t, t.TempDir(), 1<<20, endpoint)
+	sendAll(t, q, "a", "b", "c")
+
+	endpoint.setDown(false)
+	endBackoff(q)
+	sendAll(t, q, "d")
+
+	// The send delivers the oldest batch and the background loop the rest
+	waitForDrain(t, q)
+	if _, received := endpoint.state(); !slices.Equal(received, []string{"a", "b", "c", "d"}) {
+		t.Errorf("endpoint received %v, want [a b c d]", received)
+	}
+
+	sendAll(t, q, "e")
+	if _, received := endpoint.state(); received[len(received)-1] != "e" {
+		t.Errorf("endpoint received %v, want e sent directly", received)
+	}
+}
+
+func TestQueuedExportFlushIgnoresBackoff(t *testing.T) {
+	endpoint := &flakyEndpoint{down: true}
+	q := newStringQueue(t, t.TempDir(), 1<<20, endpoint)
+	sendAll(t, q, "a", "b")
+
+	endpoint.setDown(false)
+	q.flush(context.Background())
+	if _, received := endpoint.state(); !slices.Equal(received, []string{"a", "b"}) {
+		t.Errorf("endpoint received %v, want [a b]", received)
+	}
+	if got := q.spooled(); got != 0 {
+		t.Errorf("spooled() = %d after flush, want 0", got)
+	}
+}
+
+func TestQueuedExportKeepsBatchesForNextProcess(t *testing.T) {
+	dir := t.TempDir()
+	endpoint := &flakyEndpoint{down: true}
+	q := newStringQueue(t, dir, 1<<20, endpoint)
+	sendAll(t, q, "a", "b")
+	q.close()
+
+	// A partially written batch is discarded on open
+	partial := q.queue.path(99) + tmpFileSuffix
+	if err := os.WriteFile(partial, []byte("partial"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	endpoint.setDown(false)
+	reopened := newStringQueue(t, dir, 1<<20, endpoint)
+	if _, err := os.Stat(partial); !os.IsNotExist(err) {
+		t.Errorf("partial batch was kept: %v", err)
+	}
+	waitForDrain(t, reopened)
+	if _, received := endpoint.state(); !slices.Equal(received, []string{"a", "b"}) {
+		t.Errorf("endpoint received %v, want [a b] from the previous run", received)
+	}
+}
+
+func TestQueuedExportEvictsOldestBatches(t *testing.T) {
+	endpoint := &flakyEndpoint{down: true}
+	q := newStringQueue(t, t.TempDir(), 3, endpoint)
+	sendAll(t, q, "a", "b", "c", "d")
+	if got := q.spooled(); got != 3 {
+		t.Errorf("spooled() = %d, want 3 within the size limit", got)
+	}
+	if err := q.send(context.Background(), "too large"); err == nil {
+		t.Error("send() of a batch over the size limit succeeded")
+	}
+
+	endpoint.setDown(false)
+	q.flush(context.Background())
+	if _, received := endpoint.state(); !slices.Equal(received, []string{"b", "c", "d"}) {
+		t.Errorf("endpoint received %v, want [b c d] without the evicted batch", received)
+	}
+}
+
+// failingSpanExporter fails while down and records spans otherwise.
+type failingSpanExporter struct {
+	*tracetest.InMemoryExporter
+	down bool
+}
+
+// ExportSpans implements trace.SpanExporter.
+func (e *failingSpanExporter) ExportSpans(ctx context.Context, spans []trace.ReadOnlySpan) error {
+	if e.down {
+		return errors.New("collector unavailable")
+	}
+	return e.InMemoryExporter.ExportSpans(ctx, spans)
+}
+
+func TestQueuedSpanExporterReplaysDecodedSpans(t *testing.T) {
+	exporter := &failingSpanExporter{InMemoryExporter: tracetest.NewInMemoryExporter(), down: true}
+	queued, err := newQueuedSpanExporter(exporter, t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatalf("newQueuedSpanExporter() error = %v", err)
+	}
+	defer queued.queue.close()
+
+	spans := tracetest.SpanStubs{{Name: "first"}, {Name: "second"}}
+	if err := queued.ExportSpans(context.Background(), spans.Snapshots()); err != nil {
+		t.Fatalf("ExportSpans() error = %v, want the batch spooled", err)
+	}
+	if got := queued.queue.spooled(); got != 1 {
+		t.Fatalf("spooled() = %d, want 1", got)
+	}
+
+	exporter.down = false
+	queued.queue.flush(context.Background())
+	var names []string
+	for _, span := range exporter.GetSpans() {
+		names = append(names, span.Name)
+	}
+	if !slices.Equal(names, []string{"first", "second"}) {
+		t.Errorf("exported spans %v, want [first second]", names)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"sync"
 	"time"
 
+	promclient "github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
@@ -63,16 +64,44 @@ func (ot *OpenTelemetry) createDefaultTraceExporter(ctx context.Context) (trace.
 
 		opts := []otlptracehttp.Option{
 			otlptracehttp.WithEndpoint(endpoint),
-			otlptracehttp.WithTimeout(30 * time.Second), // Add 30 second timeout
+			otlptracehttp.WithTimeout(ot.exportTimeout(signalTraces)),
 		}
 
 		if ot.config.OTLPHeaders != nil {
 			opts = append(opts, otlptracehttp.WithHeaders(ot.config.OTLPHeaders))
 		}
 
+		gzip, err := ot.exportCompression(signalTraces)
+		if err != nil {
+			return nil, err
+		}
+		if gzip {
+			opts = append(opts, otlptracehttp.WithCompression(otlptracehttp.GzipCompression))
+		}
+
+		if ot.config.OTLPRetry != nil {
+			retry := ot.config.OTLPRetry.withDefaults()
+			opts = append(opts, otlptracehttp.WithRetry(otlptracehttp.RetryConfig{
+				Enabled:         !retry.Disabled,
+				InitialInterval: retry.InitialInterval,
+				MaxInterval:     retry.MaxInterval,
+				MaxElapsedTime:  retry.MaxElapsedTime,
+			}))
+		}
+
 		// Configure TLS based on the original scheme
 		if useTLS {
 			opts = append(opts, otlptracehttp.WithTLSClientConfig(&tls.Config{}))
+
+			// Authenticate against the Google Cloud Telemetry API, like
+			// the gRPC exporter below
+			if ot.isPreset(PresetGoogleCloud) {
+				client, err := ot.createGoogleCloudHTTPClient(ctx, ot.exportTimeout(signalTraces))
+				if err != nil {
+					return nil, err
+				}
+				opts = append(opts, otlptracehttp.WithHTTPClient(client))
+			}
 		} else {
 			opts = append(opts, otlptracehttp.WithInsecure())
 		}
@@ -84,19 +113,47 @@ func (ot *OpenTelemetry) createDefaultTraceExporter(ctx context.Context) (trace.
 
 		opts := []otlptracegrpc.Option{
 			otlptracegrpc.WithEndpoint(endpoint),
-			otlptracegrpc.WithTimeout(30 * time.Second), // Add 30 second timeout
+			otlptracegrpc.WithTimeout(ot.exportTimeout(signalTraces)),
 		}
 
 		if ot.config.OTLPHeaders != nil {
 			opts = append(opts, otlptracegrpc.WithHeaders(ot.config.OTLPHeaders))
 		}
 
+		gzip, err := ot.exportCompression(signalTraces)
+		if err != nil {
+			return nil, err
+		}
+		if gzip {
+			opts = append(opts, otlptracegrpc.WithCompressor(string(CompressionGzip)))
+		}
+
+		if ot.config.OTLPRetry != nil {
+			retry := ot.config.OTLPRetry.withDefaults()
+			opts = append(opts, otlptracegrpc.WithRetry(otlptracegrpc.RetryConfig{
+				Enabled:         !retry.Disabled,
+				InitialInterval: retry.InitialInterval,
+				MaxInterval:     retry.MaxInterval,
+				MaxElapsedTime:  retry.MaxElapsedTime,
+			}))
+		}
+
 		// Configure gRPC connection
 		dialOpts := []grpc.DialOption{}
 
 		// If original endpoint starts with https, use TLS
 		if len(ot.config.OTLPEndpoint) > 8 && ot.config.OTLPEndpoint[:8] == "https://" {
 			dialOpts = append(dialOpts, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{})))
+
+			// Authenticate against the Google Cloud Telemetry API. Plaintext
+			// endpoints (e.g. a local collector) are left unauthenticated.
+			if ot.isPreset(PresetGoogleCloud) {
+				creds, err := ot.createGoogleCloudCredentials(ctx)
+				if err != nil {
+					return nil, err
+				}
+				dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(creds))
+			}
 		} else {
 			opts = append(opts, otlptracegrpc.WithInsecure())
 		}
@@ -111,9 +168,21 @@ func (ot *OpenTelemetry) createDefaultTraceExporter(ctx context.Context) (trace.
 
 // createDefaultMetricExporter creates the default OTLP metric exporter.
 func (ot *OpenTelemetry) createDefaultMetricExporter(ctx context.Context) (metric.Exporter, error) {
+	var selector metric.TemporalitySelector
+	if temporality := ot.metricTemporality(); temporality != "" {
+		var err error
+		if selector, err = temporality.selector(); err != nil {
+			return nil, err
+		}
+	}
+
 	// If OTEL_EXPORTER_OTLP_METRICS_ENDPOINT is "stdout", use stdout exporter
 	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_METRICS_ENDPOINT"); endpoint == "stdout" {
-		return stdoutmetric.New(stdoutmetric.WithPrettyPrint())
+		opts := []stdoutmetric.Option{stdoutmetric.WithPrettyPrint()}
+		if selector != nil {
+			opts = append(opts, stdoutmetric.WithTemporalitySelector(selector))
+		}
+		return stdoutmetric.New(opts...)
 	}
 
 	if ot.config.OTLPUseHTTP {
@@ -129,16 +198,48 @@ func (ot *OpenTelemetry) createDefaultMetricExporter(ctx context.Context) (metri
 
 		opts := []otlpmetrichttp.Option{
 			otlpmetrichttp.WithEndpoint(endpoint),
-			otlpmetrichttp.WithTimeout(30 * time.Second), // Add 30 second timeout
+			otlpmetrichttp.WithTimeout(ot.exportTimeout(signalMetrics)),
 		}
 
 		if ot.config.OTLPHeaders != nil {
 			opts = append(opts, otlpmetrichttp.WithHeaders(ot.config.OTLPHeaders))
 		}
 
+		gzip, err := ot.exportCompression(signalMetrics)
+		if err != nil {
+			return nil, err
+		}
+		if gzip {
+			opts = append(opts, otlpmetrichttp.WithCompression(otlpmetrichttp.GzipCompression))
+		}
+
+		if ot.config.OTLPRetry != nil {
+			retry := ot.config.OTLPRetry.withDefaults()
+			opts = append(opts, otlpmetrichttp.WithRetry(otlpmetrichttp.RetryConfig{
+				Enabled:         !retry.Disabled,
+				InitialInterval: retry.InitialInterval,
+				MaxInterval:     retry.MaxInterval,
+				MaxElapsedTime:  retry.MaxElapsedTime,
+			}))
+		}
+
+		if selector != nil {
+			opts = append(opts, otlpmetrichttp.WithTemporalitySelector(selector))
+		}
+
 		// Configure TLS based on the original scheme
 		if useTLS {
 			opts = append(opts, otlpmetrichttp.WithTLSClientConfig(&tls.Config{}))
+
+			// Authenticate against the Google Cloud Telemetry API, like
+			// the gRPC exporter below
+			if ot.isPreset(PresetGoogleCloud) {
+				client, err := ot.createGoogleCloudHTTPClient(ctx, ot.exportTimeout(signalMetrics))
+				if err != nil {
+					return nil, err
+				}
+				opts = append(opts, otlpmetrichttp.WithHTTPClient(client))
+			}
 		} else {
 			opts = append(opts, otlpmetrichttp.WithInsecure())
 		}
@@ -150,19 +251,51 @@ func (ot *OpenTelemetry) createDefaultMetricExporter(ctx context.Context) (metri
 
 		opts := []otlpmetricgrpc.Option{
 			otlpmetricgrpc.WithEndpoint(endpoint),
-			otlpmetricgrpc.WithTimeout(30 * time.Second), // Add 30 second timeout
+			otlpmetricgrpc.WithTimeout(ot.exportTimeout(signalMetrics)),
 		}
 
 		if ot.config.OTLPHeaders != nil {
 			opts = append(opts, otlpmetricgrpc.WithHeaders(ot.config.OTLPHeaders))
 		}
 
+		gzip, err := ot.exportCompression(signalMetrics)
+		if err != nil {
+			return nil, err
+		}
+		if gzip {
+			opts = append(opts, otlpmetricgrpc.WithCompressor(string(CompressionGzip)))
+		}
+
+		if ot.config.OTLPRetry != nil {
+			retry := ot.config.OTLPRetry.withDefaults()
+			opts = append(opts, otlpmetricgrpc.WithRetry(otlpmetricgrpc.RetryConfig{
+				Enabled:         !retry.Disabled,
+				InitialInterval: retry.InitialInterval,
+				MaxInterval:     retry.MaxInterval,
+				MaxElapsedTime:  retry.MaxElapsedTime,
+			}))
+		}
+
+		if selector != nil {
+			opts = append(opts, otlpmetricgrpc.WithTemporalitySelector(selector))
+		}
+
 		// Configure gRPC connection
 		dialOpts := []grpc.DialOption{}
 
 		// If original endpoint starts with https, use TLS
 		if len(ot.config.OTLPEndpoint) > 8 && ot.config.OTLPEndpoint[:8] == "https://" {
 			dialOpts = append(dialOpts, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{})))
+
+			// Authenticate against the Google Cloud Telemetry API. Plaintext
+			// endpoints (e.g. a local collector) are left unauthenticated.
+			if ot.isPreset(PresetGoogleCloud) {
+				creds, err := ot.createGoogleCloudCredentials(ctx)
+				if err != nil {
+					return nil, err
+				}
+				dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(creds))
+			}
 		} else {
 			opts = append(opts, otlpmetricgrpc.WithInsecure())
 		}
@@ -178,7 +311,7 @@ func (ot *OpenTelemetry) createDefaultMetricExporter(ctx context.Context) (metri
 // createDefaultLogHandler creates the default structured log handler.
 func (ot *OpenTelemetry) createDefaultLogHandler() slog.Handler {
 	opts := &slog.HandlerOptions{
-		Level: ot.config.LogLevel,
+		Level: ot.logLevel,
 	}
 
 	// Use JSON handler for structured logging
@@ -198,14 +331,21 @@ func (ot *OpenTelemetry) setupPrometheusMetrics(ctx context.Context) error {
 		return ot.setupMetrics(ctx)
 	}
 
+	if temporality := ot.config.MetricTemporality; temporality != "" && temporality != TemporalityCumulative {
+		slog.Warn("Prometheus metrics are always cumulative, ignoring MetricTemporality", "temporality", temporality)
+	}
+
 	// Create Prometheus exporter
 	exporter, err := prometheus.New()
 	if err != nil {
 		return err
 	}
 
-	meterProvider := metric.NewMeterProvider(metric.WithReader(exporter))
-	otel.SetMeterProvider(meterProvider)
+	ot.meterProvider, err = ot.newMeterProvider(exporter)
+	if err != nil {
+		return err
+	}
+	otel.SetMeterProvider(ot.meterProvider)
 
 	// Start HTTP server for /metrics endpoint if enabled
 	if ot.config.EnablePrometheusEndpoint {
@@ -220,7 +360,14 @@ func (ot *OpenTelemetry) setupPrometheusMetrics(ctx context.Context) error {
 		}
 
 		mux := http.NewServeMux()
-		mux.Handle("/metrics", promhttp.Handler())
+		// Exemplars are only exposed in the OpenMetrics format
+		mux.Handle("/metrics", promhttp.InstrumentMetricHandler(
+			promclient.DefaultRegisterer,
+			promhttp.HandlerFor(ot.stats.observedGatherer(promclient.DefaultGatherer), promhttp.HandlerOpts{EnableOpenMetrics: true}),
+		))
+		if ot.config.EnableHealthEndpoint {
+			mux.Handle(healthPath, ot.HealthHandler())
+		}
 
 		// Create server context for graceful shutdown
 		serverCtx, serverCancel := context.WithCancel(context.Background())
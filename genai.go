@@ -0,0 +1,279 @@
+package opentelemetry
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/firebase/genkit/go/ai"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+// OpenTelemetry GenAI semantic convention attributes.
+const (
+	genAISystemAttr             = "gen_ai.system"
+	genAIOperationNameAttr      = "gen_ai.operation.name"
+	genAIRequestModelAttr       = "gen_ai.request.model"
+	genAIRequestTemperatureAttr = "gen_ai.request.temperature"
+	genAIRequestMaxTokensAttr   = "gen_ai.request.max_tokens"
+	genAIRequestTopPAttr        = "gen_ai.request.top_p"
+	genAIRequestTopKAttr        = "gen_ai.request.top_k"
+	genAIUsageInputTokensAttr   = "gen_ai.usage.input_tokens"
+	genAIUsageOutputTokensAttr  = "gen_ai.usage.output_tokens"
+	genAIFinishReasonsAttr      = "gen_ai.response.finish_reasons"
+	genAIToolNameAttr           = "gen_ai.tool.name"
+	genAIPromptAttr             = "gen_ai.prompt"
+	genAICompletionAttr         = "gen_ai.completion"
+)
+
+// Events holding the message content of model spans.
+const (
+	genAIPromptEvent     = "gen_ai.content.prompt"
+	genAICompletionEvent = "gen_ai.content.completion"
+)
+
+// Values of gen_ai.operation.name.
+const (
+	genAIOperationChat        = "chat"
+	genAIOperationEmbeddings  = "embeddings"
+	genAIOperationExecuteTool = "execute_tool"
+)
+
+// genAISystems maps Genkit plugin prefixes of model names to gen_ai.system
+// values. Other prefixes are used as they are.
+var genAISystems = map[string]string{
+	"googleai":       "gcp.gemini",
+	"vertexai":       "gcp.vertex_ai",
+	"openai":         "openai",
+	"anthropic":      "anthropic",
+	"awsbedrock":     "aws.bedrock",
+	"bedrock":        "aws.bedrock",
+	"mistral":        "mistral_ai",
+	"deepseek":       "deepseek",
+	"xai":            "xai",
+	"groq":           "groq",
+	"azureaifoundry": "az.ai.inference",
+	"azureopenai":    "az.ai.openai",
+}
+
+// GenAIConventions configures the translation of Genkit span attributes to
+// the OpenTelemetry GenAI semantic conventions, understood by backends such
+// as Datadog LLM Observability, Langfuse and Arize.
+type GenAIConventions struct {
+	// Remove the genkit:* attributes from translated model, embedder and
+	// tool spans. Defaults to keeping them, which the Genkit Developer UI
+	// and Google Cloud need.
+	DropGenkitAttributes bool `json:"dropGenkitAttributes,omitempty"`
+
+	// Leave out the prompt and completion events, which hold the message
+	// content. Flows with a debug override always record them.
+	OmitContent bool `json:"omitContent,omitempty"`
+}
+
+// genAITransform adds GenAI semantic convention attributes and events to
+// model, embedder and tool spans, following the current conventions, or
+// none if they are nil. debugging reports whether a flow has a debug
+// override.
+func genAITransform(current *atomic.Pointer[GenAIConventions], debugging func(flow string) bool, payloads *spanPayloads) spanTransform {
+	return func(span trace.ReadOnlySpan) trace.ReadOnlySpan {
+		conventions := current.Load()
+		if conventions == nil {
+			return span
+		}
+		attrs := span.Attributes()
+		if stringAttribute(attrs, genkitTypeAttr) != "action" {
+			return span
+		}
+
+		name := stringAttribute(attrs, genkitNameAttr)
+		var added []attribute.KeyValue
+		var events []trace.Event
+		switch stringAttribute(attrs, genkitSubtypeAttr) {
+		case "model":
+			input, _ := decodePayload[genAIModelRequest](payloads, span, genkitInputAttr)
+			output := modelResponse(payloads, span)
+			added = genAIModelAttributes(name, input, output)
+			if !conventions.OmitContent || debugging(featureNameFromPath(stringAttribute(attrs, genkitPathAttr))) {
+				events = genAIContentEvents(span, input, output)
+			}
+		case "embedder":
+			added = append(genAIModelIdentity(name), attribute.String(genAIOperationNameAttr, genAIOperationEmbeddings))
+		case "tool":
+			added = []attribute.KeyValue{
+				attribute.String(genAIOperationNameAttr, genAIOperationExecuteTool),
+				attribute.String(genAIToolNameAttr, name),
+			}
+		default:
+			return span
+		}
+
+		translated := make([]attribute.KeyValue, 0, len(attrs)+len(added))
+		for _, attr := range attrs {
+			if !conventions.DropGenkitAttributes || !strings.HasPrefix(string(attr.Key), "genkit:") {
+				translated = append(translated, attr)
+			}
+		}
+		span = &spanWithAttributes{ReadOnlySpan: span, attrs: append(translated, added...)}
+		if len(events) > 0 {
+			span = &spanWithEvents{ReadOnlySpan: span, events: append(slices.Clone(span.Events()), events...)}
+		}
+		return span
+	}
+}
+
+// genAIModelIdentity returns gen_ai.system and gen_ai.request.model for a
+// Genkit model or embedder name such as "googleai/gemini-2.5-flash".
+func genAIModelIdentity(name string) []attribute.KeyValue {
+	system, model, found := strings.Cut(name, "/")
+	if !found {
+		system, model = "genkit", name
+	}
+	if mapped, ok := genAISystems[system]; ok {
+		system = mapped
+	}
+	return []attribute.KeyValue{
+		attribute.String(genAISystemAttr, system),
+		attribute.String(genAIRequestModelAttr, model),
+	}
+}
+
+// genAIModelRequest is the part of a model request read for the GenAI
+// conventions.
+type genAIModelRequest struct {
+	Messages []*ai.Message `json:"messages"`
+
+	// Model configs are plugin specific, but most use Genkit's common names
+	Config struct {
+		Temperature     *float64 `json:"temperature"`
+		MaxOutputTokens *int     `json:"maxOutputTokens"`
+		TopP            *float64 `json:"topP"`
+		TopK            *int     `json:"topK"`
+	} `json:"config"`
+}
+
+// genAIModelAttributes returns the request and response attributes of a
+// model span.
+func genAIModelAttributes(name string, input genAIModelRequest, output ai.ModelResponse) []attribute.KeyValue {
+	added := append(genAIModelIdentity(name), attribute.String(genAIOperationNameAttr, genAIOperationChat))
+
+	if v := input.Config.Temperature; v != nil {
+		added = append(added, attribute.Float64(genAIRequestTemperatureAttr, *v))
+	}
+	if v := input.Config.MaxOutputTokens; v != nil {
+		added = append(added, attribute.Int(genAIRequestMaxTokensAttr, *v))
+	}
+	if v := input.Config.TopP; v != nil {
+		added = append(added, attribute.Float64(genAIRequestTopPAttr, *v))
+	}
+	if v := input.Config.TopK; v != nil {
+		added = append(added, attribute.Int(genAIRequestTopKAttr, *v))
+	}
+
+	if output.FinishReason != "" {
+		added = append(added, attribute.StringSlice(genAIFinishReasonsAttr, []string{string(output.FinishReason)}))
+	}
+	if usage := output.Usage; usage != nil {
+		added = append(added,
+			attribute.Int(genAIUsageInputTokensAttr, usage.InputTokens),
+			attribute.Int(genAIUsageOutputTokensAttr, usage.OutputTokens))
+	}
+	return added
+}
+
+// genAIMessage is a chat message in the shape GenAI backends expect.
+type genAIMessage struct {
+	Role       string          `json:"role"`
+	Content    string          `json:"content,omitempty"`
+	ToolCalls  []genAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string          `json:"tool_call_id,omitempty"`
+}
+
+// genAIToolCall is a tool call requested by the model.
+type genAIToolCall struct {
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments,omitempty"`
+	} `json:"function"`
+}
+
+// genAIContentEvents returns the prompt and completion events of a model
+// span, timed at its start and end.
+func genAIContentEvents(span trace.ReadOnlySpan, input genAIModelRequest, output ai.ModelResponse) []trace.Event {
+	var events []trace.Event
+
+	var prompt []genAIMessage
+	for _, message := range input.Messages {
+		prompt = append(prompt, genAIMessages(message)...)
+	}
+	if data, err := json.Marshal(prompt); err == nil && len(prompt) > 0 {
+		events = append(events, trace.Event{
+			Name:       genAIPromptEvent,
+			Attributes: []attribute.KeyValue{attribute.String(genAIPromptAttr, string(data))},
+			Time:       span.StartTime(),
+		})
+	}
+
+	if output.Message != nil {
+		if data, err := json.Marshal(genAIMessages(output.Message)); err == nil {
+			events = append(events, trace.Event{
+				Name:       genAICompletionEvent,
+				Attributes: []attribute.KeyValue{attribute.String(genAICompletionAttr, string(data))},
+				Time:       span.EndTime(),
+			})
+		}
+	}
+	return events
+}
+
+// genAIMessages converts a Genkit message. Tool responses become one message
+// per response, as each answers a separate call.
+func genAIMessages(message *ai.Message) []genAIMessage {
+	if message == nil {
+		return nil
+	}
+
+	role := string(message.Role)
+	if message.Role == ai.RoleModel {
+		role = "assistant"
+	}
+	converted := genAIMessage{Role: role}
+	var responses []genAIMessage
+	var text strings.Builder
+	for _, part := range message.Content {
+		switch {
+		case part.IsToolRequest():
+			call := genAIToolCall{ID: part.ToolRequest.Ref, Type: "function"}
+			call.Function.Name = part.ToolRequest.Name
+			if data, err := json.Marshal(part.ToolRequest.Input); err == nil {
+				call.Function.Arguments = string(data)
+			}
+			converted.ToolCalls = append(converted.ToolCalls, call)
+		case part.IsToolResponse():
+			data, _ := json.Marshal(part.ToolResponse.Output)
+			responses = append(responses, genAIMessage{Role: "tool", Content: string(data), ToolCallID: part.ToolResponse.Ref})
+		default:
+			text.WriteString(part.Text)
+		}
+	}
+	converted.Content = text.String()
+
+	if len(responses) > 0 && converted.Content == "" && len(converted.ToolCalls) == 0 {
+		return responses
+	}
+	return append([]genAIMessage{converted}, responses...)
+}
+
+// spanWithEvents overrides the events of a finished span.
+type spanWithEvents struct {
+	trace.ReadOnlySpan
+	events []trace.Event
+}
+
+// Events implements trace.ReadOnlySpan.
+func (s *spanWithEvents) Events() []trace.Event {
+	return s.events
+}
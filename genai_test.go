@@ -0,0 +1,187 @@
+package opentelemetry_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+	opentelemetry "github.com/xavidop/genkit-opentelemetry-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// eventAttribute returns the value of key on the named event of span, and
+// whether the event exists.
+func eventAttribute(span tracetest.SpanStub, event, key string) (string, bool) {
+	for _, e := range span.Events {
+		if e.Name != event {
+			continue
+		}
+		for _, attr := range e.Attributes {
+			if string(attr.Key) == key {
+				return attr.Value.AsString(), true
+			}
+		}
+		return "", true
+	}
+	return "", false
+}
+
+func TestGenAIModelSpans(t *testing.T) {
+	tel, g := newTestGenkit(t, opentelemetry.Config{GenAIConventions: &opentelemetry.GenAIConventions{}})
+	flow := defineGenerateFlow(g, "genAIFlow",
+		ai.WithSystem("be brief"),
+		ai.WithConfig(&ai.GenerationCommonConfig{Temperature: 0.5, MaxOutputTokens: 100, TopK: 40, TopP: 0.9}))
+	runFlow(t, flow, "hi")
+
+	span := tel.AssertSpan(testModel,
+		attribute.String("gen_ai.system", "test"),
+		attribute.String("gen_ai.request.model", "model"),
+		attribute.String("gen_ai.operation.name", "chat"),
+		attribute.Float64("gen_ai.request.temperature", 0.5),
+		attribute.Int("gen_ai.request.max_tokens", 100),
+		attribute.Int("gen_ai.request.top_k", 40),
+		attribute.Float64("gen_ai.request.top_p", 0.9),
+		attribute.Int("gen_ai.usage.input_tokens", 1000),
+		attribute.Int("gen_ai.usage.output_tokens", 500))
+	if got := attributeValue(span, "gen_ai.response.finish_reasons").AsStringSlice(); len(got) != 1 || got[0] != "stop" {
+		t.Errorf("gen_ai.response.finish_reasons = %v, want [stop]", got)
+	}
+	if !hasAttribute(span, "genkit:output") {
+		t.Error("genkit:* attributes were removed without DropGenkitAttributes")
+	}
+
+	prompt, ok := eventAttribute(span, "gen_ai.content.prompt", "gen_ai.prompt")
+	if !ok {
+		t.Fatal("no prompt event")
+	}
+	var messages []map[string]any
+	if err := json.Unmarshal([]byte(prompt), &messages); err != nil {
+		t.Fatalf("gen_ai.prompt is not JSON: %v", err)
+	}
+	if len(messages) != 2 || messages[0]["role"] != "system" || messages[0]["content"] != "be brief" ||
+		messages[1]["role"] != "user" || messages[1]["content"] != "hi" {
+		t.Errorf("gen_ai.prompt = %s, want the system and user messages", prompt)
+	}
+	completion, ok := eventAttribute(span, "gen_ai.content.completion", "gen_ai.completion")
+	if !ok {
+		t.Fatal("no completion event")
+	}
+	if want := `[{"role":"assistant","content":"hello"}]`; completion != want {
+		t.Errorf("gen_ai.completion = %s, want %s", completion, want)
+	}
+
+	flowSpan := tel.AssertSpan("genAIFlow")
+	if hasAttribute(flowSpan, "gen_ai.operation.name") {
+		t.Error("flow span was translated")
+	}
+}
+
+func TestGenAIDisabledByDefault(t *testing.T) {
+	tel, g := newTestGenkit(t)
+	runFlow(t, defineGenerateFlow(g, "plainFlow"), "hi")
+
+	span := tel.AssertSpan(testModel)
+	if hasAttribute(span, "gen_ai.operation.name") || len(span.Events) > 0 {
+		t.Errorf("model span was translated without GenAIConventions: %v", span.Attributes)
+	}
+}
+
+func TestGenAIDropGenkitAttributesAndOmitContent(t *testing.T) {
+	tel, g := newTestGenkit(t, opentelemetry.Config{GenAIConventions: &opentelemetry.GenAIConventions{
+		DropGenkitAttributes: true,
+		OmitContent:          true,
+	}})
+	runFlow(t, defineGenerateFlow(g, "privateFlow"), "hi")
+
+	span := tel.AssertSpan(testModel, attribute.String("gen_ai.operation.name", "chat"))
+	for _, attr := range span.Attributes {
+		if strings.HasPrefix(string(attr.Key), "genkit:") {
+			t.Errorf("model span has %s", attr.Key)
+		}
+	}
+	if len(span.Events) > 0 {
+		t.Errorf("model span has content events %v", span.Events)
+	}
+	// Metrics are derived before the translation
+	tel.AssertMetric("genkit/ai/generate/input/tokens", 1000, attribute.String("modelName", testModel))
+}
+
+func TestGenAIContentOfDebuggedFlow(t *testing.T) {
+	tel, g := newTestGenkit(t, opentelemetry.Config{GenAIConventions: &opentelemetry.GenAIConventions{OmitContent: true}})
+	flow := defineGenerateFlow(g, "debuggedFlow")
+	tel.Plugin.RegisterFlows(g)
+	if _, err := tel.Plugin.EnableDebug("debuggedFlow", 0); err != nil {
+		t.Fatalf("EnableDebug() error = %v", err)
+	}
+	runFlow(t, flow, "hi")
+
+	span := tel.AssertSpan(testModel, attribute.String("gen_ai.operation.name", "chat"))
+	if _, ok := eventAttribute(span, "gen_ai.content.prompt", "gen_ai.prompt"); !ok {
+		t.Error("debugged flow has no prompt event")
+	}
+}
+
+func TestGenAIEmbedderAndToolSpans(t *testing.T) {
+	tel, g := newTestGenkit(t, opentelemetry.Config{GenAIConventions: &opentelemetry.GenAIConventions{}})
+	genkit.DefineEmbedder(g, "googleai/text-embedding", nil, func(ctx context.Context, req *ai.EmbedRequest) (*ai.EmbedResponse, error) {
+		resp := &ai.EmbedResponse{}
+		for range req.Input {
+			resp.Embeddings = append(resp.Embeddings, &ai.Embedding{Embedding: []float32{1, 0}})
+		}
+		return resp, nil
+	})
+	weather := genkit.DefineTool(g, "weather", "Returns the weather", func(ctx *ai.ToolContext, city string) (string, error) {
+		return "sunny", nil
+	})
+	genkit.DefineModel(g, "test/agent", &ai.ModelOptions{Supports: &ai.ModelSupports{Multiturn: true, Tools: true}},
+		func(ctx context.Context, req *ai.ModelRequest, cb ai.ModelStreamCallback) (*ai.ModelResponse, error) {
+			last := req.Messages[len(req.Messages)-1]
+			if last.Role == ai.RoleTool {
+				return &ai.ModelResponse{Message: ai.NewModelTextMessage("it is sunny")}, nil
+			}
+			return &ai.ModelResponse{Message: ai.NewModelMessage(ai.NewToolRequestPart(&ai.ToolRequest{
+				Name: "weather", Input: "Paris", Ref: "call-1",
+			}))}, nil
+		})
+	flow := genkit.DefineFlow(g, "agentFlow", func(ctx context.Context, input string) (string, error) {
+		if _, err := genkit.Embed(ctx, g, ai.WithEmbedderName("googleai/text-embedding"), ai.WithTextDocs(input)); err != nil {
+			return "", err
+		}
+		resp, err := genkit.Generate(ctx, g, ai.WithModelName("test/agent"), ai.WithPrompt(input), ai.WithTools(weather))
+		if err != nil {
+			return "", err
+		}
+		return resp.Text(), nil
+	})
+	runFlow(t, flow, "weather in Paris?")
+
+	tel.AssertSpan("googleai/text-embedding",
+		attribute.String("gen_ai.system", "gcp.gemini"),
+		attribute.String("gen_ai.request.model", "text-embedding"),
+		attribute.String("gen_ai.operation.name", "embeddings"))
+	tel.AssertSpan("weather",
+		attribute.String("gen_ai.operation.name", "execute_tool"),
+		attribute.String("gen_ai.tool.name", "weather"))
+	tel.AssertMetric("genkit/ai/tool/requests", 1,
+		attribute.String("toolName", "weather"),
+		attribute.String("featureName", "agentFlow"))
+
+	var completions []string
+	for _, span := range tel.FindFlowTrace("agentFlow") {
+		if span.Name == "test/agent" {
+			completion, _ := eventAttribute(span, "gen_ai.content.completion", "gen_ai.completion")
+			completions = append(completions, completion)
+		}
+	}
+	want := []string{
+		`[{"role":"assistant","tool_calls":[{"id":"call-1","type":"function","function":{"name":"weather","arguments":"\"Paris\""}}]}]`,
+		`[{"role":"assistant","content":"it is sunny"}]`,
+	}
+	if len(completions) != len(want) || completions[0] != want[0] || completions[1] != want[1] {
+		t.Errorf("completions = %q, want %q", completions, want)
+	}
+}
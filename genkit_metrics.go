@@ -0,0 +1,280 @@
+package opentelemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	otelmetric "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+// genkitMeterName is the instrumentation scope used for Genkit metrics.
+const genkitMeterName = "genkit"
+
+// genkitMetrics is a span processor that derives Genkit feature, path and
+// generate metrics from finished spans. Metric names and dimensions match the
+// ones emitted by the official Genkit googlecloud plugin, so dashboards built
+// on top of that plugin keep working.
+type genkitMetrics struct {
+	featureRequests  otelmetric.Int64Counter
+	featureLatency   otelmetric.Float64Histogram
+	pathRequests     otelmetric.Int64Counter
+	pathLatency      otelmetric.Float64Histogram
+	generateRequests otelmetric.Int64Counter
+	generateLatency  otelmetric.Int64Histogram
+	inputCharacters  otelmetric.Int64Counter
+	inputTokens      otelmetric.Int64Counter
+	inputImages      otelmetric.Int64Counter
+	inputVideos      otelmetric.Int64Counter
+	inputAudio       otelmetric.Int64Counter
+	outputCharacters otelmetric.Int64Counter
+	outputTokens     otelmetric.Int64Counter
+	thinkingTokens   otelmetric.Int64Counter
+	outputImages     otelmetric.Int64Counter
+	outputVideos     otelmetric.Int64Counter
+	outputAudio      otelmetric.Int64Counter
+	cost             otelmetric.Float64Counter
+	tokens           otelmetric.Int64Histogram
+
+	// Span attributes added to every metric, e.g. promoted baggage keys
+	dimensionKeys []string
+
+	// Model prices used to derive the cost metric
+	pricing PricingTable
+
+	// Decoded span payloads shared with the other processors and transforms
+	payloads *spanPayloads
+}
+
+// newGenkitMetrics creates the Genkit metric instruments on the given meter
+// provider. dimensionKeys are span attributes copied onto every data point.
+func newGenkitMetrics(mp otelmetric.MeterProvider, dimensionKeys []string, pricing PricingTable, payloads *spanPayloads) (*genkitMetrics, error) {
+	meter := mp.Meter(genkitMeterName)
+	m := &genkitMetrics{dimensionKeys: dimensionKeys, pricing: pricing, payloads: payloads}
+
+	counters := []struct {
+		dst         *otelmetric.Int64Counter
+		name        string
+		description string
+	}{
+		{&m.featureRequests, "genkit/feature/requests", "Counts calls to genkit features."},
+		{&m.pathRequests, "genkit/feature/path/requests", "Tracks unique flow paths per flow."},
+		{&m.generateRequests, "genkit/ai/generate/requests", "Counts calls to genkit generate actions."},
+		{&m.inputCharacters, "genkit/ai/generate/input/characters", "Counts input characters to any Genkit model."},
+		{&m.inputTokens, "genkit/ai/generate/input/tokens", "Counts input tokens to a Genkit model."},
+		{&m.inputImages, "genkit/ai/generate/input/images", "Counts input images to a Genkit model."},
+		{&m.inputVideos, "genkit/ai/generate/input/videos", "Counts input videos to a Genkit model."},
+		{&m.inputAudio, "genkit/ai/generate/input/audio", "Counts input audio files to a Genkit model."},
+		{&m.outputCharacters, "genkit/ai/generate/output/characters", "Counts output characters from a Genkit model."},
+		{&m.outputTokens, "genkit/ai/generate/output/tokens", "Counts output tokens from a Genkit model."},
+		{&m.thinkingTokens, "genkit/ai/generate/thinking/tokens", "Counts thinking tokens from a Genkit model."},
+		{&m.outputImages, "genkit/ai/generate/output/images", "Count output images from a Genkit model."},
+		{&m.outputVideos, "genkit/ai/generate/output/videos", "Count output videos from a Genkit model."},
+		{&m.outputAudio, "genkit/ai/generate/output/audio", "Count output audio files from a Genkit model."},
+	}
+	for _, c := range counters {
+		counter, err := meter.Int64Counter(c.name, otelmetric.WithDescription(c.description), otelmetric.WithUnit("1"))
+		if err != nil {
+			return nil, err
+		}
+		*c.dst = counter
+	}
+
+	var err error
+	if m.featureLatency, err = meter.Float64Histogram("genkit/feature/latency",
+		otelmetric.WithDescription("Latencies when calling Genkit features."),
+		otelmetric.WithUnit("ms"),
+		otelmetric.WithExplicitBucketBoundaries(DefaultDurationBucketsMs...)); err != nil {
+		return nil, err
+	}
+	if m.pathLatency, err = meter.Float64Histogram("genkit/feature/path/latency",
+		otelmetric.WithDescription("Latencies per flow path."),
+		otelmetric.WithUnit("ms"),
+		otelmetric.WithExplicitBucketBoundaries(DefaultDurationBucketsMs...)); err != nil {
+		return nil, err
+	}
+	if m.generateLatency, err = meter.Int64Histogram("genkit/ai/generate/latency",
+		otelmetric.WithDescription("Latencies when interacting with a Genkit model."),
+		otelmetric.WithUnit("ms"),
+		otelmetric.WithExplicitBucketBoundaries(DefaultDurationBucketsMs...)); err != nil {
+		return nil, err
+	}
+	if m.tokens, err = meter.Int64Histogram("genkit/ai/generate/tokens",
+		otelmetric.WithDescription("Distribution of input and output tokens per call to a Genkit model."),
+		otelmetric.WithUnit("{token}"),
+		otelmetric.WithExplicitBucketBoundaries(DefaultTokenBuckets...)); err != nil {
+		return nil, err
+	}
+	if m.cost, err = meter.Float64Counter("genkit/ai/generate/cost",
+		otelmetric.WithDescription("Cost of calls to a Genkit model derived from token usage."),
+		otelmetric.WithUnit("USD")); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// OnStart implements trace.SpanProcessor.
+func (m *genkitMetrics) OnStart(context.Context, trace.ReadWriteSpan) {}
+
+// OnEnd implements trace.SpanProcessor.
+func (m *genkitMetrics) OnEnd(span trace.ReadOnlySpan) {
+	attrs := span.Attributes()
+	spanType := stringAttribute(attrs, genkitTypeAttr)
+	if spanType == "" {
+		return // Not a Genkit span
+	}
+
+	ctx := spanContext(span)
+	m.recordPath(ctx, span)
+
+	if boolAttribute(attrs, genkitIsRootAttr) {
+		m.recordFeature(ctx, span)
+	} else if spanType == "action" && stringAttribute(attrs, genkitSubtypeAttr) == "model" {
+		m.recordGenerate(ctx, span)
+	}
+}
+
+// Shutdown implements trace.SpanProcessor.
+func (m *genkitMetrics) Shutdown(context.Context) error { return nil }
+
+// ForceFlush implements trace.SpanProcessor.
+func (m *genkitMetrics) ForceFlush(context.Context) error { return nil }
+
+// recordFeature records request and latency metrics for root spans.
+func (m *genkitMetrics) recordFeature(ctx context.Context, span trace.ReadOnlySpan) {
+	attrs := span.Attributes()
+	dims := []attribute.KeyValue{
+		attribute.String("name", stringAttribute(attrs, genkitNameAttr)),
+		attribute.String("source", "go"),
+		attribute.String("sourceVersion", genkitSourceVersion()),
+	}
+	dims = append(dims, promotedAttributes(attrs, m.dimensionKeys)...)
+
+	switch stringAttribute(attrs, genkitStateAttr) {
+	case "success":
+		dims = append(dims, attribute.String("status", "success"))
+	case "error":
+		errorName := spanErrorName(span)
+		if errorName == "" {
+			errorName = "<unknown>"
+		}
+		dims = append(dims, attribute.String("status", "failure"), attribute.String("error", errorName))
+	default:
+		return
+	}
+
+	opt := otelmetric.WithAttributes(dims...)
+	m.featureRequests.Add(ctx, 1, opt)
+	m.featureLatency.Record(ctx, spanLatencyMs(span), opt)
+}
+
+// recordPath records request and latency metrics for the span that caused a failure.
+func (m *genkitMetrics) recordPath(ctx context.Context, span trace.ReadOnlySpan) {
+	attrs := span.Attributes()
+	path := stringAttribute(attrs, genkitPathAttr)
+	if path == "" || !boolAttribute(attrs, genkitIsFailureSourceAttr) || stringAttribute(attrs, genkitStateAttr) != "error" {
+		return
+	}
+
+	dims := []attribute.KeyValue{
+		attribute.String("featureName", featureNameFromPath(path)),
+		attribute.String("status", "failure"),
+		attribute.String("error", "Error"),
+		attribute.String("path", path),
+		attribute.String("source", "go"),
+		attribute.String("sourceVersion", genkitSourceVersion()),
+	}
+	dims = append(dims, promotedAttributes(attrs, m.dimensionKeys)...)
+
+	opt := otelmetric.WithAttributes(dims...)
+	m.pathRequests.Add(ctx, 1, opt)
+	m.pathLatency.Record(ctx, spanLatencyMs(span), opt)
+}
+
+// recordGenerate records request, latency and usage metrics for model actions.
+func (m *genkitMetrics) recordGenerate(ctx context.Context, span trace.ReadOnlySpan) {
+	attrs := span.Attributes()
+	if stringAttribute(attrs, genkitInputAttr) == "" {
+		return
+	}
+
+	path := stringAttribute(attrs, genkitPathAttr)
+	featureName := featureNameFromPath(path)
+	if featureName == "<unknown>" {
+		featureName = "generate"
+	}
+
+	errorName := spanErrorName(span)
+	status := "success"
+	if errorName != "" {
+		status = "failure"
+	}
+
+	dims := []attribute.KeyValue{
+		attribute.String("modelName", truncate(stringAttribute(attrs, genkitNameAttr), 1024)),
+		attribute.String("featureName", featureName),
+		attribute.String("path", path),
+		attribute.String("status", status),
+		attribute.String("source", "go"),
+		attribute.String("sourceVersion", genkitSourceVersion()),
+	}
+	dims = append(dims, promotedAttributes(attrs, m.dimensionKeys)...)
+	if errorName != "" {
+		m.generateRequests.Add(ctx, 1, otelmetric.WithAttributes(append(dims, attribute.String("error", errorName))...))
+	} else {
+		m.generateRequests.Add(ctx, 1, otelmetric.WithAttributes(dims...))
+	}
+
+	output := modelResponse(m.payloads, span)
+
+	opt := otelmetric.WithAttributes(dims...)
+	if output.LatencyMs > 0 {
+		m.generateLatency.Record(ctx, int64(output.LatencyMs), opt)
+	}
+
+	if usage := output.Usage; usage != nil {
+		m.inputTokens.Add(ctx, int64(usage.InputTokens), opt)
+		m.inputCharacters.Add(ctx, int64(usage.InputCharacters), opt)
+		m.inputImages.Add(ctx, int64(usage.InputImages), opt)
+		m.inputVideos.Add(ctx, int64(usage.InputVideos), opt)
+		m.inputAudio.Add(ctx, int64(usage.InputAudioFiles), opt)
+		m.outputTokens.Add(ctx, int64(usage.OutputTokens), opt)
+		m.outputCharacters.Add(ctx, int64(usage.OutputCharacters), opt)
+		m.thinkingTokens.Add(ctx, int64(usage.ThoughtsTokens), opt)
+		m.outputImages.Add(ctx, int64(usage.OutputImages), opt)
+		m.outputVideos.Add(ctx, int64(usage.OutputVideos), opt)
+		m.outputAudio.Add(ctx, int64(usage.OutputAudioFiles), opt)
+
+		m.tokens.Record(ctx, int64(usage.InputTokens), otelmetric.WithAttributes(append(dims, attribute.String("tokenType", "input"))...))
+		m.tokens.Record(ctx, int64(usage.OutputTokens), otelmetric.WithAttributes(append(dims, attribute.String("tokenType", "output"))...))
+	}
+
+	if cost, ok := m.pricing.modelCost(span, m.payloads); ok {
+		m.cost.Add(ctx, cost, opt)
+	}
+}
+
+// spanErrorName returns the error recorded on a span, if any.
+func spanErrorName(span trace.ReadOnlySpan) string {
+	if span.Status().Code == codes.Error {
+		return span.Status().Description
+	}
+	for _, event := range span.Events() {
+		if event.Name == "exception" {
+			if errorType := stringAttribute(event.Attributes, "exception.type"); errorType != "" {
+				return errorType
+			}
+		}
+	}
+	return ""
+}
+
+// truncate shortens s to at most limit bytes.
+func truncate(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	return s[:limit]
+}
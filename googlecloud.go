@@ -0,0 +1,90 @@
+package opentelemetry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/oauth"
+)
+
+// googleCloudScope is the OAuth scope required to write traces and metrics.
+const googleCloudScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// googleCloudCredentials authenticates OTLP gRPC calls against the Google
+// Cloud Telemetry API and sets the quota project for the request.
+type googleCloudCredentials struct {
+	credentials.PerRPCCredentials
+	projectID string
+}
+
+// GetRequestMetadata implements credentials.PerRPCCredentials.
+func (c *googleCloudCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	md, err := c.PerRPCCredentials.GetRequestMetadata(ctx, uri...)
+	if err != nil {
+		return nil, err
+	}
+	if c.projectID != "" {
+		md["x-goog-user-project"] = c.projectID
+	}
+	return md, nil
+}
+
+// Overridden in tests to run against a local stand-in of the Telemetry API.
+var (
+	googleCloudTokenSource = func(ctx context.Context) (oauth2.TokenSource, error) {
+		return google.DefaultTokenSource(ctx, googleCloudScope)
+	}
+	googleCloudTransport = http.DefaultTransport
+)
+
+// createGoogleCloudCredentials returns gRPC credentials authenticating OTLP
+// calls against the Google Cloud Telemetry API with Application Default
+// Credentials.
+func (ot *OpenTelemetry) createGoogleCloudCredentials(ctx context.Context) (credentials.PerRPCCredentials, error) {
+	source, err := googleCloudTokenSource(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Google Cloud application default credentials: %w", err)
+	}
+	return &googleCloudCredentials{
+		PerRPCCredentials: oauth.TokenSource{TokenSource: source},
+		projectID:         ot.config.GoogleCloudProjectID,
+	}, nil
+}
+
+// createGoogleCloudHTTPClient returns an HTTP client authenticating OTLP
+// requests against the Google Cloud Telemetry API with Application Default
+// Credentials. The exporters ignore their timeout option when given a client,
+// so it is set on the client.
+func (ot *OpenTelemetry) createGoogleCloudHTTPClient(ctx context.Context, timeout time.Duration) (*http.Client, error) {
+	source, err := googleCloudTokenSource(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Google Cloud application default credentials: %w", err)
+	}
+	return &http.Client{
+		Transport: &oauth2.Transport{
+			Source: source,
+			Base:   &googleCloudProjectTransport{base: googleCloudTransport, projectID: ot.config.GoogleCloudProjectID},
+		},
+		Timeout: timeout,
+	}, nil
+}
+
+// googleCloudProjectTransport sets the quota project of HTTP requests.
+type googleCloudProjectTransport struct {
+	base      http.RoundTripper
+	projectID string
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *googleCloudProjectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.projectID != "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("x-goog-user-project", t.projectID)
+	}
+	return t.base.RoundTrip(req)
+}
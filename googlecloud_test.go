@@ -0,0 +1,210 @@
+package opentelemetry
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+// fakeTelemetryAPI is a local stand-in for the Google Cloud Telemetry API
+// recording the headers of each request by path.
+func fakeTelemetryAPI(t *testing.T) (*httptest.Server, map[string]http.Header) {
+	t.Helper()
+
+	var mu sync.Mutex
+	headers := map[string]http.Header{}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		headers[r.URL.Path] = r.Header.Clone()
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	t.Cleanup(server.Close)
+
+	stubGoogleCloudToken(t)
+	transport := googleCloudTransport
+	googleCloudTransport = server.Client().Transport
+	t.Cleanup(func() { googleCloudTransport = transport })
+
+	return server, headers
+}
+
+// stubGoogleCloudToken replaces Application Default Credentials with a
+// static "test-token" access token.
+func stubGoogleCloudToken(t *testing.T) {
+	t.Helper()
+	tokenSource := googleCloudTokenSource
+	googleCloudTokenSource = func(context.Context) (oauth2.TokenSource, error) {
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "test-token"}), nil
+	}
+	t.Cleanup(func() { googleCloudTokenSource = tokenSource })
+}
+
+func TestGoogleCloudHTTPExportersAuthenticate(t *testing.T) {
+	server, headers := fakeTelemetryAPI(t)
+	ctx := context.Background()
+
+	ot := NewWithPreset(PresetGoogleCloud, Config{
+		OTLPEndpoint:         server.URL,
+		OTLPUseHTTP:          true,
+		GoogleCloudProjectID: "my-project",
+	})
+
+	traceExporter, err := ot.createDefaultTraceExporter(ctx)
+	if err != nil {
+		t.Fatalf("createDefaultTraceExporter() error = %v", err)
+	}
+	spans := tracetest.SpanStubs{{Name: "span", StartTime: time.Now(), EndTime: time.Now()}}
+	if err := traceExporter.ExportSpans(ctx, spans.Snapshots()); err != nil {
+		t.Fatalf("ExportSpans() error = %v", err)
+	}
+
+	metricExporter, err := ot.createDefaultMetricExporter(ctx)
+	if err != nil {
+		t.Fatalf("createDefaultMetricExporter() error = %v", err)
+	}
+	metrics := &metricdata.ResourceMetrics{ScopeMetrics: []metricdata.ScopeMetrics{{
+		Metrics: []metricdata.Metrics{{
+			Name: "requests",
+			Data: metricdata.Sum[int64]{
+				Temporality: metricdata.CumulativeTemporality,
+				DataPoints:  []metricdata.DataPoint[int64]{{Value: 1}},
+			},
+		}},
+	}}}
+	if err := metricExporter.Export(ctx, metrics); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	for _, path := range []string{"/v1/traces", "/v1/metrics"} {
+		header, ok := headers[path]
+		if !ok {
+			t.Fatalf("no request to %s", path)
+		}
+		if got, want := header.Get("Authorization"), "Bearer test-token"; got != want {
+			t.Errorf("%s Authorization = %q, want %q", path, got, want)
+		}
+		if got, want := header.Get("x-goog-user-project"), "my-project"; got != want {
+			t.Errorf("%s x-goog-user-project = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestGoogleCloudGRPCExportersAuthenticate(t *testing.T) {
+	stubGoogleCloudToken(t)
+	ctx := context.Background()
+
+	// The preset exports over gRPC to the Telemetry API by default
+	ot := NewWithPreset(PresetGoogleCloud, Config{GoogleCloudProjectID: "my-project"})
+	if ot.config.OTLPUseHTTP || ot.config.OTLPEndpoint != googleCloudOTLPEndpoint {
+		t.Fatalf("preset exports to %s with HTTP %v, want gRPC to %s", ot.config.OTLPEndpoint, ot.config.OTLPUseHTTP, googleCloudOTLPEndpoint)
+	}
+	if _, err := ot.createDefaultTraceExporter(ctx); err != nil {
+		t.Fatalf("createDefaultTraceExporter() error = %v", err)
+	}
+	if _, err := ot.createDefaultMetricExporter(ctx); err != nil {
+		t.Fatalf("createDefaultMetricExporter() error = %v", err)
+	}
+
+	creds, err := ot.createGoogleCloudCredentials(ctx)
+	if err != nil {
+		t.Fatalf("createGoogleCloudCredentials() error = %v", err)
+	}
+	md := exportOverGRPC(t, creds)
+	if got, want := md.Get("authorization"), []string{"Bearer test-token"}; !slices.Equal(got, want) {
+		t.Errorf("authorization = %q, want %q", got, want)
+	}
+	if got, want := md.Get("x-goog-user-project"), []string{"my-project"}; !slices.Equal(got, want) {
+		t.Errorf("x-goog-user-project = %q, want %q", got, want)
+	}
+}
+
+// exportOverGRPC exports an empty batch of spans with creds to a local TLS
+// stand-in of the Telemetry API and returns the metadata it received.
+func exportOverGRPC(t *testing.T, creds credentials.PerRPCCredentials) metadata.MD {
+	t.Helper()
+
+	// Borrow the certificate of a TLS test server and the pool trusting it
+	certServer := httptest.NewTLSServer(nil)
+	cert := certServer.TLS.Certificates[0]
+	roots := certServer.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+	certServer.Close()
+
+	var received metadata.MD
+	server := grpc.NewServer(
+		grpc.Creds(credentials.NewServerTLSFromCert(&cert)),
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, _ grpc.UnaryHandler) (any, error) {
+			received, _ = metadata.FromIncomingContext(ctx)
+			return &collectortrace.ExportTraceServiceResponse{}, nil
+		}),
+	)
+	collectortrace.RegisterTraceServiceServer(server, collectortrace.UnimplementedTraceServiceServer{})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	conn, err := grpc.NewClient(listener.Addr().String(),
+		grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{RootCAs: roots})),
+		grpc.WithPerRPCCredentials(creds))
+	if err != nil {
+		t.Fatalf("grpc.NewClient() error = %v", err)
+	}
+	defer conn.Close()
+	client := collectortrace.NewTraceServiceClient(conn)
+	if _, err := client.Export(context.Background(), &collectortrace.ExportTraceServiceRequest{}); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	return received
+}
+
+func TestGoogleCloudCredentialsError(t *testing.T) {
+	tokenSource := googleCloudTokenSource
+	googleCloudTokenSource = func(context.Context) (oauth2.TokenSource, error) {
+		return nil, errors.New("no credentials")
+	}
+	defer func() { googleCloudTokenSource = tokenSource }()
+
+	ot := NewWithPreset(PresetGoogleCloud)
+	if _, err := ot.createDefaultTraceExporter(context.Background()); err == nil {
+		t.Error("createDefaultTraceExporter() succeeded without credentials")
+	}
+}
+
+func TestGoogleCloudPlaintextEndpointIsUnauthenticated(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	ot := NewWithPreset(PresetGoogleCloud, Config{OTLPEndpoint: server.URL, OTLPUseHTTP: true})
+	exporter, err := ot.createDefaultTraceExporter(context.Background())
+	if err != nil {
+		t.Fatalf("createDefaultTraceExporter() error = %v", err)
+	}
+	spans := tracetest.SpanStubs{{Name: "span"}}
+	if err := exporter.ExportSpans(context.Background(), spans.Snapshots()); err != nil {
+		t.Fatalf("ExportSpans() error = %v", err)
+	}
+	if authorization != "" {
+		t.Errorf("Authorization = %q, want none for a local collector", authorization)
+	}
+}
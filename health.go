@@ -0,0 +1,128 @@
+package opentelemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Statuses reported by HealthHandler.
+const (
+	// HealthStatusOK means telemetry is being exported.
+	HealthStatusOK = "ok"
+
+	// HealthStatusUnhealthy means exports keep failing or a queue is close
+	// to dropping data.
+	HealthStatusUnhealthy = "unhealthy"
+
+	// HealthStatusNotReady means the plugin has not been initialized yet or
+	// has been shut down.
+	HealthStatusNotReady = "not_ready"
+)
+
+// Default thresholds for reporting a signal unhealthy.
+const (
+	defaultHealthFailureThreshold = 3
+	defaultHealthQueueThreshold   = 0.9
+)
+
+// healthPath is where the health handler is served on the Prometheus server.
+const healthPath = "/healthz"
+
+// HealthReport is the state of the telemetry pipeline served by
+// HealthHandler.
+type HealthReport struct {
+	// Overall status, unhealthy if any signal is.
+	Status string `json:"status"`
+
+	// State of each signal, by "traces" and "metrics".
+	Signals map[string]SignalHealth `json:"signals,omitempty"`
+}
+
+// SignalHealth is the export state of one signal.
+type SignalHealth struct {
+	Status string `json:"status"`
+
+	// Time of the latest successful export, omitted if there was none.
+	LastSuccessfulExport *time.Time `json:"lastSuccessfulExport,omitempty"`
+
+	// Exports failed in a row since the latest successful one.
+	ConsecutiveFailures int64 `json:"consecutiveFailures"`
+
+	// Fraction of the fullest queue in use, from 0 to 1: the span queue
+	// for traces, or the export queue if Config.ExportQueueDir is set.
+	QueueFillRatio float64 `json:"queueFillRatio"`
+
+	// Why the signal is unhealthy.
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// Health returns the current state of the telemetry pipeline. A signal is
+// unhealthy once Config.HealthFailureThreshold exports failed in a row or a
+// queue is Config.HealthQueueThreshold full.
+func (ot *OpenTelemetry) Health() HealthReport {
+	if !ot.ready.Load() {
+		return HealthReport{Status: HealthStatusNotReady}
+	}
+
+	stats := ot.stats.snapshot()
+	traceQueueFill := stats.Traces.ExportQueueFill
+	if stats.SpanQueueCapacity > 0 {
+		traceQueueFill = max(traceQueueFill, float64(stats.SpanQueueSize)/float64(stats.SpanQueueCapacity))
+	}
+
+	report := HealthReport{
+		Status: HealthStatusOK,
+		Signals: map[string]SignalHealth{
+			signalTraces:  ot.signalHealth(stats.Traces, traceQueueFill),
+			signalMetrics: ot.signalHealth(stats.Metrics, stats.Metrics.ExportQueueFill),
+		},
+	}
+	for _, signal := range report.Signals {
+		if signal.Status != HealthStatusOK {
+			report.Status = HealthStatusUnhealthy
+		}
+	}
+	return report
+}
+
+// signalHealth evaluates the health thresholds for one signal.
+func (ot *OpenTelemetry) signalHealth(stats SignalStats, queueFill float64) SignalHealth {
+	health := SignalHealth{
+		Status:              HealthStatusOK,
+		ConsecutiveFailures: stats.ConsecutiveFailures,
+		QueueFillRatio:      queueFill,
+	}
+	if !stats.LastSuccess.IsZero() {
+		health.LastSuccessfulExport = &stats.LastSuccess
+	}
+
+	if threshold := ot.config.HealthFailureThreshold; stats.ConsecutiveFailures >= int64(threshold) {
+		health.Reasons = append(health.Reasons, fmt.Sprintf("%d consecutive export failures", stats.ConsecutiveFailures))
+	}
+	if threshold := ot.config.HealthQueueThreshold; queueFill >= threshold {
+		health.Reasons = append(health.Reasons, fmt.Sprintf("queue %.0f%% full", queueFill*100))
+	}
+	if len(health.Reasons) > 0 {
+		health.Status = HealthStatusUnhealthy
+	}
+	return health
+}
+
+// HealthHandler returns an http.Handler serving Health as JSON, with status
+// 200 when healthy and 503 otherwise. Use it as a readiness probe rather
+// than a liveness probe: restarting the process does not fix an unreachable
+// collector.
+func (ot *OpenTelemetry) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		report := ot.Health()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if report.Status != HealthStatusOK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
@@ -0,0 +1,89 @@
+package opentelemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// getHealth requests ot's health handler and decodes the report.
+func getHealth(t *testing.T, ot *OpenTelemetry) (int, HealthReport) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	ot.HealthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, healthPath, nil))
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+	var report HealthReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("decoding %q: %v", rec.Body.String(), err)
+	}
+	return rec.Code, report
+}
+
+func TestHealthHandler(t *testing.T) {
+	ot := New(Config{HealthQueueThreshold: 0.5})
+	if code, report := getHealth(t, ot); code != http.StatusServiceUnavailable || report.Status != HealthStatusNotReady {
+		t.Errorf("before Init: %d %q, want 503 %q", code, report.Status, HealthStatusNotReady)
+	}
+
+	ot.ready.Store(true)
+	ot.stats.spanQueueCapacity = 10
+	ot.stats.exported(context.Background(), signalTraces, time.Millisecond, nil)
+
+	// Healthy
+	code, report := getHealth(t, ot)
+	if code != http.StatusOK || report.Status != HealthStatusOK {
+		t.Fatalf("healthy: %d %q, want 200 %q", code, report.Status, HealthStatusOK)
+	}
+	traces := report.Signals[signalTraces]
+	if traces.Status != HealthStatusOK || traces.LastSuccessfulExport == nil || len(traces.Reasons) != 0 {
+		t.Errorf("healthy traces = %+v, want ok with a last successful export", traces)
+	}
+	if metrics, ok := report.Signals[signalMetrics]; !ok || metrics.LastSuccessfulExport != nil {
+		t.Errorf("healthy metrics = %+v, want no successful export yet", metrics)
+	}
+
+	// Degraded by the span queue filling up
+	ot.stats.spanQueueSize.Store(6)
+	code, report = getHealth(t, ot)
+	if code != http.StatusServiceUnavailable || report.Status != HealthStatusUnhealthy {
+		t.Fatalf("queue full: %d %q, want 503 %q", code, report.Status, HealthStatusUnhealthy)
+	}
+	traces = report.Signals[signalTraces]
+	if traces.Status != HealthStatusUnhealthy || traces.QueueFillRatio != 0.6 || len(traces.Reasons) != 1 || traces.Reasons[0] != "queue 60% full" {
+		t.Errorf("queue full traces = %+v, want unhealthy at 0.6", traces)
+	}
+	if report.Signals[signalMetrics].Status != HealthStatusOK {
+		t.Errorf("queue full metrics = %+v, want ok", report.Signals[signalMetrics])
+	}
+
+	// Degraded by failing exports
+	ot.stats.spanQueueSize.Store(0)
+	for range defaultHealthFailureThreshold {
+		ot.stats.exported(context.Background(), signalMetrics, time.Millisecond, errors.New("boom"))
+	}
+	code, report = getHealth(t, ot)
+	if code != http.StatusServiceUnavailable || report.Status != HealthStatusUnhealthy {
+		t.Fatalf("failing exports: %d %q, want 503 %q", code, report.Status, HealthStatusUnhealthy)
+	}
+	if metrics := report.Signals[signalMetrics]; metrics.ConsecutiveFailures != 3 || len(metrics.Reasons) != 1 {
+		t.Errorf("failing metrics = %+v, want 3 consecutive failures", metrics)
+	}
+	if report.Signals[signalTraces].Status != HealthStatusOK {
+		t.Errorf("failing exports traces = %+v, want ok", report.Signals[signalTraces])
+	}
+
+	// After Shutdown
+	if err := ot.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	code, report = getHealth(t, ot)
+	if code != http.StatusServiceUnavailable || report.Status != HealthStatusNotReady || report.Signals != nil {
+		t.Errorf("after Shutdown: %d %+v, want 503 %q", code, report, HealthStatusNotReady)
+	}
+}
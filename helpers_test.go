@@ -0,0 +1,103 @@
+package opentelemetry_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/core"
+	"github.com/firebase/genkit/go/genkit"
+	opentelemetry "github.com/xavidop/genkit-opentelemetry-go"
+	"github.com/xavidop/genkit-opentelemetry-go/testutil"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// testModel is the name of the model defined by newTestGenkit.
+const testModel = "test/model"
+
+// newTestGenkit initializes Genkit with a plugin exporting to memory and
+// defines testModel, which answers "hello" using 1000 input and 500 output
+// tokens.
+func newTestGenkit(t *testing.T, config ...opentelemetry.Config) (*testutil.Telemetry, *genkit.Genkit) {
+	t.Helper()
+
+	tel := testutil.NewForTest(t, config...)
+	g := genkit.Init(context.Background(), genkit.WithPlugins(tel.Plugin))
+	genkit.DefineModel(g, testModel, &ai.ModelOptions{Supports: &ai.ModelSupports{Multiturn: true, SystemRole: true}},
+		func(ctx context.Context, req *ai.ModelRequest, cb ai.ModelStreamCallback) (*ai.ModelResponse, error) {
+			return &ai.ModelResponse{
+				Message:      ai.NewModelTextMessage("hello"),
+				FinishReason: ai.FinishReasonStop,
+				Usage:        &ai.GenerationUsage{InputTokens: 1000, OutputTokens: 500},
+			}, nil
+		})
+	return tel, g
+}
+
+// defineGenerateFlow defines a flow that prompts testModel with its input.
+func defineGenerateFlow(g *genkit.Genkit, name string, opts ...ai.GenerateOption) *core.Flow[string, string, struct{}] {
+	return genkit.DefineFlow(g, name, func(ctx context.Context, input string) (string, error) {
+		resp, err := genkit.Generate(ctx, g, append([]ai.GenerateOption{ai.WithModelName(testModel), ai.WithPrompt(input)}, opts...)...)
+		if err != nil {
+			return "", err
+		}
+		return resp.Text(), nil
+	})
+}
+
+// runFlow runs flow and fails the test if it returns an error.
+func runFlow(t *testing.T, flow *core.Flow[string, string, struct{}], input string) {
+	t.Helper()
+	if _, err := flow.Run(context.Background(), input); err != nil {
+		t.Fatalf("flow %s failed: %v", flow.Name(), err)
+	}
+}
+
+// spanByName returns the first span with the given name.
+func spanByName(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	t.Helper()
+	for _, span := range spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	t.Fatalf("no span %q", name)
+	return tracetest.SpanStub{}
+}
+
+// hasSpan reports whether spans contains a span with the given name.
+func hasSpan(spans tracetest.SpanStubs, name string) bool {
+	for _, span := range spans {
+		if span.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAttribute reports whether span has an attribute with the given key.
+func hasAttribute(span tracetest.SpanStub, key string) bool {
+	for _, attr := range span.Attributes {
+		if string(attr.Key) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// attributeValue returns the value of key on span.
+func attributeValue(span tracetest.SpanStub, key string) attribute.Value {
+	for _, attr := range span.Attributes {
+		if string(attr.Key) == key {
+			return attr.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+// parentID returns the span ID of the parent of span.
+func parentID(span tracetest.SpanStub) oteltrace.SpanID {
+	return span.Parent.SpanID()
+}
@@ -0,0 +1,82 @@
+package opentelemetry
+
+import (
+	"go.opentelemetry.io/otel/sdk/metric"
+)
+
+var (
+	// DefaultDurationBucketsMs are the bucket boundaries used for the plugin's
+	// latency histograms. They cover fast tool calls as well as model calls
+	// and flows that take minutes.
+	DefaultDurationBucketsMs = []float64{
+		10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 20000, 30000, 60000, 120000, 300000, 600000,
+	}
+
+	// DefaultTokenBuckets are the bucket boundaries used for the plugin's
+	// token count histograms, up to one million tokens.
+	DefaultTokenBuckets = []float64{
+		1, 10, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 25000, 50000, 100000, 250000, 500000, 1000000,
+	}
+
+	// DefaultChunkBucketsMs are the bucket boundaries used for the gap
+	// between streamed chunks, from a few milliseconds up to long stalls.
+	DefaultChunkBucketsMs = []float64{
+		1, 2.5, 5, 10, 25, 50, 75, 100, 250, 500, 1000, 2500, 5000, 10000, 30000,
+	}
+
+	// DefaultThroughputBuckets are the bucket boundaries used for the
+	// streaming output throughput histogram, in tokens per second.
+	DefaultThroughputBuckets = []float64{
+		1, 5, 10, 20, 30, 40, 50, 75, 100, 150, 200, 300, 500, 1000, 2500,
+	}
+)
+
+// Exponential histogram settings. 160 buckets at scale 20 are downscaled as
+// needed and keep a relative error below 5% across durations and token counts.
+const (
+	exponentialHistogramMaxSize  = 160
+	exponentialHistogramMaxScale = 20
+)
+
+// isPluginScope reports whether an instrumentation scope belongs to the plugin.
+func isPluginScope(name string) bool {
+	return name == genkitMeterName || name == instrumentationName
+}
+
+// histogramView applies the configured histogram aggregation. Explicit
+// buckets in Config.HistogramBuckets win over exponential histograms, and any
+// instrument already matched by one of Config.MetricViews is left alone so it
+// is not exported twice.
+func (ot *OpenTelemetry) histogramView() metric.View {
+	return func(inst metric.Instrument) (metric.Stream, bool) {
+		if inst.Kind != metric.InstrumentKindHistogram {
+			return metric.Stream{}, false
+		}
+		for _, view := range ot.config.MetricViews {
+			if _, ok := view(inst); ok {
+				return metric.Stream{}, false
+			}
+		}
+
+		stream := metric.Stream{
+			Name:        inst.Name,
+			Description: inst.Description,
+			Unit:        inst.Unit,
+		}
+
+		if boundaries, ok := ot.config.HistogramBuckets[inst.Name]; ok {
+			stream.Aggregation = metric.AggregationExplicitBucketHistogram{Boundaries: boundaries}
+			return stream, true
+		}
+
+		if ot.config.ExponentialHistograms && isPluginScope(inst.Scope.Name) {
+			stream.Aggregation = metric.AggregationBase2ExponentialHistogram{
+				MaxSize:  exponentialHistogramMaxSize,
+				MaxScale: exponentialHistogramMaxScale,
+			}
+			return stream, true
+		}
+
+		return metric.Stream{}, false
+	}
+}
@@ -0,0 +1,72 @@
+package opentelemetry
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	otelmetric "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestHistogramAggregation(t *testing.T) {
+	customView := metric.NewView(metric.Instrument{Name: "viewed"}, metric.Stream{
+		Aggregation: metric.AggregationExplicitBucketHistogram{Boundaries: []float64{7}},
+	})
+	tests := []struct {
+		name        string
+		config      Config
+		scope       string
+		instrument  string
+		exponential bool
+		wantBounds  []float64
+	}{
+		{"plugin default buckets", Config{}, genkitMeterName, "latency", false, DefaultDurationBucketsMs},
+		{"exponential for plugin instruments", Config{ExponentialHistograms: true}, genkitMeterName, "latency", true, nil},
+		{"exponential for the middleware", Config{ExponentialHistograms: true}, instrumentationName, "latency", true, nil},
+		{"other scopes keep explicit buckets", Config{ExponentialHistograms: true}, "other", "latency", false, DefaultDurationBucketsMs},
+		{"custom buckets win over exponential", Config{
+			ExponentialHistograms: true,
+			HistogramBuckets:      map[string][]float64{"latency": {1, 2, 3}},
+		}, genkitMeterName, "latency", false, []float64{1, 2, 3}},
+		{"custom buckets for any scope", Config{
+			HistogramBuckets: map[string][]float64{"latency": {5}},
+		}, "other", "latency", false, []float64{5}},
+		{"metric views win", Config{
+			ExponentialHistograms: true,
+			HistogramBuckets:      map[string][]float64{"viewed": {1}},
+			MetricViews:           []metric.View{customView},
+		}, genkitMeterName, "viewed", false, []float64{7}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, reader := newManualMeterProvider(t, tt.config)
+			histogram, err := provider.Meter(tt.scope).Float64Histogram(tt.instrument,
+				otelmetric.WithExplicitBucketBoundaries(DefaultDurationBucketsMs...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			histogram.Record(context.Background(), 1200)
+
+			switch data := collectMetric(t, reader, tt.instrument).Data.(type) {
+			case metricdata.ExponentialHistogram[float64]:
+				if !tt.exponential {
+					t.Fatalf("got an exponential histogram, want bounds %v", tt.wantBounds)
+				}
+				if got := data.DataPoints[0].Scale; got > exponentialHistogramMaxScale {
+					t.Errorf("scale = %d, want at most %d", got, exponentialHistogramMaxScale)
+				}
+			case metricdata.Histogram[float64]:
+				if tt.exponential {
+					t.Fatalf("got bounds %v, want an exponential histogram", data.DataPoints[0].Bounds)
+				}
+				if got := data.DataPoints[0].Bounds; !slices.Equal(got, tt.wantBounds) {
+					t.Errorf("bounds = %v, want %v", got, tt.wantBounds)
+				}
+			default:
+				t.Fatalf("aggregation = %T, want a histogram", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package opentelemetry
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/firebase/genkit/go/core/api"
+	"github.com/firebase/genkit/go/genkit"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	otelmetric "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// TraceIDHeader is the response header that carries the trace ID of a request.
+const TraceIDHeader = "X-Trace-Id"
+
+// instrumentationName is the instrumentation scope used by this package.
+const instrumentationName = "github.com/xavidop/genkit-opentelemetry-go"
+
+// httpDurationBuckets extends the semantic convention buckets to cover
+// flows that wait on model calls for minutes.
+var httpDurationBuckets = []float64{
+	0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10, 30, 60, 120, 300,
+}
+
+// handler is an http.Handler that traces and measures incoming requests.
+type handler struct {
+	next     http.Handler
+	route    string
+	tracer   oteltrace.Tracer
+	duration otelmetric.Float64Histogram
+}
+
+// NewHandler wraps h so every request continues the trace propagated by the
+// caller under a server span, returns its trace ID in the X-Trace-Id header and
+// is recorded in the http.server.request.duration metric. route is used as
+// the http.route attribute and span name suffix and may be empty.
+func NewHandler(h http.Handler, route string) http.Handler {
+	duration, err := otel.Meter(instrumentationName).Float64Histogram(
+		"http.server.request.duration",
+		otelmetric.WithDescription("Duration of HTTP server requests."),
+		otelmetric.WithUnit("s"),
+		otelmetric.WithExplicitBucketBoundaries(httpDurationBuckets...),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	return &handler{
+		next:     h,
+		route:    route,
+		tracer:   otel.Tracer(instrumentationName),
+		duration: duration,
+	}
+}
+
+// FlowHandler returns genkit.Handler for the given flow or action wrapped with
+// NewHandler, so the Genkit flow span becomes a child of the request span.
+func FlowHandler(a api.Action, opts ...genkit.HandlerOption) http.Handler {
+	return NewHandler(genkit.Handler(a, opts...), "/"+a.Name())
+}
+
+// ServeHTTP implements http.Handler.
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+	spanName := r.Method
+	if h.route != "" {
+		spanName = r.Method + " " + h.route
+	}
+
+	ctx, span := h.tracer.Start(ctx, spanName,
+		oteltrace.WithSpanKind(oteltrace.SpanKindServer),
+		oteltrace.WithAttributes(h.requestAttributes(r)...),
+	)
+	defer span.End()
+
+	if sc := span.SpanContext(); sc.HasTraceID() {
+		w.Header().Set(TraceIDHeader, sc.TraceID().String())
+	}
+
+	rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+	h.next.ServeHTTP(rw, r.WithContext(ctx))
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(rw.status))
+	if rw.status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(rw.status))
+	}
+
+	if h.duration != nil {
+		attrs := []attribute.KeyValue{
+			semconv.HTTPRequestMethodKey.String(r.Method),
+			semconv.URLScheme(scheme(r)),
+			semconv.HTTPResponseStatusCode(rw.status),
+		}
+		if h.route != "" {
+			attrs = append(attrs, semconv.HTTPRoute(h.route))
+		}
+		h.duration.Record(ctx, time.Since(start).Seconds(), otelmetric.WithAttributes(attrs...))
+	}
+}
+
+// requestAttributes returns the semantic convention attributes for a request.
+func (h *handler) requestAttributes(r *http.Request) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		semconv.HTTPRequestMethodKey.String(r.Method),
+		semconv.URLPath(r.URL.Path),
+		semconv.URLScheme(scheme(r)),
+		semconv.NetworkProtocolVersion(fmt.Sprintf("%d.%d", r.ProtoMajor, r.ProtoMinor)),
+	}
+	if h.route != "" {
+		attrs = append(attrs, semconv.HTTPRoute(h.route))
+	}
+	if host, port, err := net.SplitHostPort(r.Host); err == nil {
+		attrs = append(attrs, semconv.ServerAddress(host))
+		if p, err := strconv.Atoi(port); err == nil {
+			attrs = append(attrs, semconv.ServerPort(p))
+		}
+	} else if r.Host != "" {
+		attrs = append(attrs, semconv.ServerAddress(r.Host))
+	}
+	if ua := r.UserAgent(); ua != "" {
+		attrs = append(attrs, semconv.UserAgentOriginal(ua))
+	}
+	if client := clientAddress(r); client != "" {
+		attrs = append(attrs, semconv.ClientAddress(client))
+	}
+	return attrs
+}
+
+// scheme returns the URL scheme of a request.
+func scheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// clientAddress returns the address of the client, preferring the first
+// X-Forwarded-For hop when the request went through a proxy.
+func clientAddress(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		first, _, _ := strings.Cut(forwarded, ",")
+		return strings.TrimSpace(first)
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// responseWriter records the status code written by the wrapped handler.
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (w *responseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements http.ResponseWriter.
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher so streaming flows keep working.
+func (w *responseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+	return nil, nil, errors.New("response writer does not support hijacking")
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
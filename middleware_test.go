@@ -0,0 +1,86 @@
+package opentelemetry_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	opentelemetry "github.com/xavidop/genkit-opentelemetry-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+func TestFlowHandlerContinuesIncomingTrace(t *testing.T) {
+	tel, g := newTestGenkit(t)
+	handler := opentelemetry.FlowHandler(defineGenerateFlow(g, "httpFlow"))
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	const parentSpanID = "00f067aa0ba902b7"
+	req := httptest.NewRequest(http.MethodPost, "/httpFlow", strings.NewReader(`{"data":"hi"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("traceparent", "00-"+traceID+"-"+parentSpanID+"-01")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d %s, want %d", rec.Code, rec.Body, http.StatusOK)
+	}
+	if got := rec.Header().Get(opentelemetry.TraceIDHeader); got != traceID {
+		t.Errorf("%s = %q, want the incoming trace ID %q", opentelemetry.TraceIDHeader, got, traceID)
+	}
+
+	server := tel.AssertSpan("POST /httpFlow", attribute.String("http.route", "/httpFlow"), attribute.Int("http.response.status_code", 200))
+	if got := server.SpanContext.TraceID().String(); got != traceID {
+		t.Errorf("server span trace ID = %s, want %s", got, traceID)
+	}
+	if got := server.Parent.SpanID().String(); got != parentSpanID || !server.Parent.IsRemote() {
+		t.Errorf("server span parent = %s, want the remote caller %s", got, parentSpanID)
+	}
+	flow := tel.AssertSpan("httpFlow")
+	if got, want := parentID(flow), server.SpanContext.SpanID(); got != want {
+		t.Errorf("flow span parent = %v, want the server span %v", got, want)
+	}
+	if got := flow.SpanContext.TraceID().String(); got != traceID {
+		t.Errorf("flow span trace ID = %s, want %s", got, traceID)
+	}
+
+	tel.AssertMetric("http.server.request.duration", 1,
+		attribute.String("http.request.method", http.MethodPost),
+		attribute.String("http.route", "/httpFlow"),
+		attribute.String("url.scheme", "http"),
+		attribute.Int("http.response.status_code", 200))
+}
+
+func TestNewHandlerStartsTraceAndRecordsErrors(t *testing.T) {
+	tel, _ := newTestGenkit(t)
+	handler := opentelemetry.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}), "")
+
+	req := httptest.NewRequest(http.MethodGet, "/broken", nil)
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	server := tel.AssertSpan(http.MethodGet,
+		attribute.String("url.path", "/broken"),
+		attribute.String("client.address", "203.0.113.7"),
+		attribute.Int("http.response.status_code", 500))
+	if server.Parent.IsValid() {
+		t.Errorf("server span parent = %v, want a new trace", server.Parent)
+	}
+	if got, want := rec.Header().Get(opentelemetry.TraceIDHeader), server.SpanContext.TraceID().String(); got != want {
+		t.Errorf("%s = %q, want %q", opentelemetry.TraceIDHeader, got, want)
+	}
+	if server.Status.Code != codes.Error {
+		t.Errorf("server span status = %v, want %v", server.Status.Code, codes.Error)
+	}
+
+	point := tel.AssertMetric("http.server.request.duration", 1,
+		attribute.String("http.request.method", http.MethodGet),
+		attribute.Int("http.response.status_code", 500))
+	if _, ok := point.Attributes.Value("http.route"); ok {
+		t.Error("duration has http.route without a route")
+	}
+}
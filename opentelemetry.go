@@ -21,19 +21,24 @@ package opentelemetry
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
 	"os"
 	"os/signal"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/firebase/genkit/go/core/api"
 	"github.com/firebase/genkit/go/core/tracing"
+	"go.opentelemetry.io/contrib/propagators/aws/xray"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
 	"go.opentelemetry.io/otel/sdk/trace"
 )
 
@@ -90,6 +95,184 @@ type Config struct {
 
 	// Force enable Prometheus metrics setup regardless of preset type. Defaults to false.
 	EnablePrometheusExporter bool
+
+	// Google Cloud project to send telemetry to when using PresetGoogleCloud.
+	// Defaults to the GOOGLE_CLOUD_PROJECT or GCLOUD_PROJECT environment variables.
+	GoogleCloudProjectID string
+
+	// Generate AWS X-Ray compatible trace IDs and propagate the
+	// X-Amzn-Trace-Id header, unless Propagators is "none". Defaults to
+	// false.
+	EnableXRay bool
+
+	// Context propagators to register globally, by name: "tracecontext",
+	// "baggage", "b3", "b3multi", "jaeger", "xray" or "none". Names are
+	// case-insensitive, and "none" disables propagation and must be used
+	// alone. Defaults to OTEL_PROPAGATORS, or "tracecontext,baggage" if
+	// unset.
+	Propagators []string
+
+	// Baggage keys (e.g. "tenant.id") copied onto every span started under a
+	// context carrying them and onto the Genkit metrics as attributes. Only
+	// listed keys are promoted, which keeps metric cardinality bounded.
+	BaggageAttributes []string
+
+	// Model prices used to derive the genkit/ai/generate/cost metric and the
+	// gen_ai.usage.cost span attribute. Entries override those in PricingFile.
+	Pricing PricingTable
+
+	// Path to a JSON pricing table loaded with LoadPricingTable.
+	PricingFile string
+
+	// Views applied to the plugin's meter provider, e.g. to rename
+	// instruments, drop attributes or change histogram buckets.
+	MetricViews []metric.View
+
+	// Maximum number of distinct attribute sets per instrument. Additional
+	// sets are collapsed into a single series with otel.metric.overflow=true.
+	// Defaults to 2000. Set to a negative value to disable the limit.
+	MetricCardinalityLimit int
+
+	// Use base-2 exponential histograms for the plugin's histogram
+	// instruments instead of explicit buckets. Defaults to false.
+	ExponentialHistograms bool
+
+	// Explicit bucket boundaries per histogram instrument name. Overrides
+	// ExponentialHistograms and the plugin's default boundaries.
+	HistogramBuckets map[string][]float64
+
+	// Aggregation temporality for pushed metrics: TemporalityCumulative,
+	// TemporalityDelta or TemporalityLowMemory. Applies to the default OTLP
+	// and stdout exporters and, when set, to a custom MetricExporter.
+	// Prometheus is always cumulative. Defaults to
+	// OTEL_EXPORTER_OTLP_METRICS_TEMPORALITY_PREFERENCE, or cumulative.
+	MetricTemporality MetricTemporality
+
+	// Register Go runtime (GC, goroutines, heap, scheduler latency) and
+	// process/host (CPU, RSS, open file descriptors) metrics on the plugin's
+	// meter provider. Defaults to false.
+	EnableRuntimeMetrics bool
+
+	// Record a span event every N streamed chunks in StreamingMiddleware. The
+	// first chunk and chunks after a stall of a second or more are always
+	// recorded. Defaults to 10. Set to a negative value to disable sampled
+	// chunk events.
+	StreamChunkEvents int
+
+	// Document metadata key holding the document ID, e.g. "id". When set,
+	// retriever spans carry the IDs of the returned documents (up to 50, each
+	// truncated to 128 characters) in genkit.retriever.document_ids.
+	DocumentIDMetadataKey string
+
+	// Derive rate, error and duration metrics (traces.span.metrics.calls and
+	// traces.span.metrics.duration) from every span, like the collector's
+	// spanmetrics connector. Defaults to false.
+	EnableSpanMetrics bool
+
+	// Dimensions of the span metrics: span attribute keys, or "span.name",
+	// "span.kind" and "status.code". Defaults to span.name, genkit:type,
+	// genkit:name and status.code.
+	SpanMetricsDimensions []string
+
+	// Bucket boundaries in milliseconds for traces.span.metrics.duration.
+	// Defaults to DefaultDurationBucketsMs.
+	SpanMetricsBuckets []float64
+
+	// Spans left out of the span metrics, as span name patterns or
+	// "attribute=value" patterns. "*" matches any sequence of characters,
+	// e.g. "GET /health*" or "genkit:type=util".
+	SpanMetricsExclude []string
+
+	// Which measurements are kept as exemplars linking metrics to traces:
+	// ExemplarFilterTraceBased, ExemplarFilterAlwaysOn or
+	// ExemplarFilterAlwaysOff. Defaults to OTEL_METRICS_EXEMPLAR_FILTER, or
+	// trace-based.
+	ExemplarFilter ExemplarFilter
+
+	// Directory of a write-ahead queue for OTLP and custom trace and metric
+	// exporters. Batches that fail to export are spooled there and replayed
+	// in order once the endpoint recovers, including after a restart. Empty
+	// disables the queue.
+	ExportQueueDir string
+
+	// Size limit of each signal's export queue. The oldest batches are dropped
+	// when it is exceeded. Defaults to 100MB.
+	ExportQueueMaxBytes int64
+
+	// Timeout of each OTLP trace export, including retries. Defaults to
+	// OTEL_EXPORTER_OTLP_TRACES_TIMEOUT or OTEL_EXPORTER_OTLP_TIMEOUT, or 30s.
+	TraceExportTimeout time.Duration
+
+	// Timeout of each OTLP metric export, including retries. Defaults to
+	// OTEL_EXPORTER_OTLP_METRICS_TIMEOUT or OTEL_EXPORTER_OTLP_TIMEOUT, or 30s.
+	MetricExportTimeout time.Duration
+
+	// Compression of OTLP payloads: CompressionGzip or CompressionNone.
+	// Defaults to OTEL_EXPORTER_OTLP_COMPRESSION (or its per-signal
+	// variants), or no compression.
+	OTLPCompression OTLPCompression
+
+	// Retry policy for failed OTLP exports. Defaults to the exporters'
+	// policy: retry after 5s, backing off up to 30s, for at most a minute.
+	OTLPRetry *RetryConfig
+
+	// Record metrics about the plugin's own pipeline under genkit_otel.*:
+	// spans exported and dropped, export failures and latency, queue sizes,
+	// metric collection duration and Prometheus scrapes. The same counters
+	// are always available from Stats. Defaults to false.
+	EnableSelfObservability bool
+
+	// Serve HealthHandler at /healthz on the Prometheus metrics server.
+	// Defaults to false.
+	EnableHealthEndpoint bool
+
+	// Consecutive export failures after which HealthHandler reports a signal
+	// unhealthy. Defaults to 3.
+	HealthFailureThreshold int
+
+	// Queue fill ratio, from 0 to 1, at which HealthHandler reports a signal
+	// unhealthy. Defaults to 0.9.
+	HealthQueueThreshold float64
+
+	// Sampler deciding which traces are recorded. Defaults to sampling every
+	// trace that is not part of an unsampled parent trace. Can be changed
+	// with Reconfigure.
+	Sampler trace.Sampler
+
+	// JSON file with a ConfigPatch, applied at startup and whenever the file
+	// changes. Empty disables watching.
+	ConfigFile string
+
+	// Serve AdminHandler on AdminAddr to turn debug telemetry on and off per
+	// flow registered with RegisterFlows. Defaults to false.
+	EnableAdminEndpoint bool
+
+	// Address of the admin server. Defaults to localhost:9091, so it only
+	// accepts local connections.
+	AdminAddr string
+
+	// Bearer token required by the admin server. Defaults to
+	// GENKIT_OTEL_ADMIN_TOKEN; the server does not start without one.
+	AdminToken string
+
+	// Rules dropping spans, or some of their attributes, before export.
+	// Metrics derived from spans still include them. Can be changed with
+	// Reconfigure.
+	SpanFilters []SpanFilter
+
+	// Attributes set on every Genkit span as it starts, e.g. deployment,
+	// region or git SHA. Unlike ResourceAttributes they can be used in span
+	// filters and span metric dimensions.
+	SpanAttributes map[string]string
+
+	// Called on every Genkit span as it starts, after SpanAttributes are set,
+	// to add computed or request-scoped attributes from the span's context.
+	SpanEnricher SpanEnricher
+
+	// Add OpenTelemetry GenAI semantic convention attributes (gen_ai.*)
+	// and prompt and completion events to model, embedder and tool spans.
+	// Nil disables the translation. Can be changed with Reconfigure.
+	GenAIConventions *GenAIConventions
 }
 
 // setDefaults sets default values for the config.
@@ -112,16 +295,57 @@ func (c *Config) setDefaults() {
 	if c.PrometheusPort == 0 {
 		c.PrometheusPort = 9090
 	}
+	if c.MetricCardinalityLimit == 0 {
+		c.MetricCardinalityLimit = defaultCardinalityLimit
+	}
+	if c.StreamChunkEvents == 0 {
+		c.StreamChunkEvents = defaultStreamChunkEvents
+	}
+	if c.Sampler == nil {
+		c.Sampler = trace.ParentBased(trace.AlwaysSample())
+	}
+	if c.HealthFailureThreshold == 0 {
+		c.HealthFailureThreshold = defaultHealthFailureThreshold
+	}
+	if c.HealthQueueThreshold == 0 {
+		c.HealthQueueThreshold = defaultHealthQueueThreshold
+	}
+	if c.ExportQueueMaxBytes == 0 {
+		c.ExportQueueMaxBytes = defaultExportQueueMaxBytes
+	}
+	if c.AdminAddr == "" {
+		c.AdminAddr = defaultAdminAddr
+	}
 }
 
 // OpenTelemetry represents the OpenTelemetry plugin.
 type OpenTelemetry struct {
-	config       Config
-	presetType   *PresetType // Optional preset type for specialized setup
-	server       *http.Server
-	serverCancel context.CancelFunc
-	serverWg     *sync.WaitGroup
-	shutdownOnce sync.Once
+	config         Config
+	presetType     *PresetType // Optional preset type for specialized setup
+	resource       *resource.Resource
+	tracerProvider *trace.TracerProvider
+	meterProvider  *metric.MeterProvider
+	server         *http.Server
+	adminServer    *http.Server
+	serverCancel   context.CancelFunc
+	serverWg       *sync.WaitGroup
+	shutdownOnce   sync.Once
+	stats          *pipelineStats
+	ready          atomic.Bool // Set once Init has set up all signals, until Shutdown
+	debug          *debugOverrides
+
+	// Settings that can be changed by Reconfigure
+	reconfigureMu    sync.Mutex
+	shutDown         bool // Set by Shutdown, guarded by reconfigureMu
+	configMu         sync.Mutex
+	reconfigured     *Config // Config after the latest Reconfigure, if any
+	logLevel         *slog.LevelVar
+	sampler          *dynamicSampler
+	spanFilters      *spanFilterProcessor
+	genAIConventions atomic.Pointer[GenAIConventions] // Nil disables the translation
+	traceExporter    *swappableSpanExporter           // Nil for a custom TraceExporter
+	metricExporter   *swappableMetricExporter         // Nil for custom or Prometheus exporters
+	watchCancel      context.CancelFunc
 }
 
 // Name implements genkit.Plugin.
@@ -129,17 +353,30 @@ func (ot *OpenTelemetry) Name() string {
 	return providerID
 }
 
-// GetConfig returns the current configuration (mainly for testing purposes).
+// GetConfig returns the current configuration (mainly for testing purposes),
+// including changes made by Reconfigure.
 func (ot *OpenTelemetry) GetConfig() Config {
+	ot.configMu.Lock()
+	defer ot.configMu.Unlock()
+	if ot.reconfigured != nil {
+		return *ot.reconfigured
+	}
 	return ot.config
 }
 
+// isPreset reports whether the plugin was created with the given preset.
+func (ot *OpenTelemetry) isPreset(preset PresetType) bool {
+	return ot.presetType != nil && *ot.presetType == preset
+}
+
 // New creates a new OpenTelemetry plugin with the given config.
 func New(config Config) *OpenTelemetry {
 	config.setDefaults()
 	return &OpenTelemetry{
 		config:   config,
 		serverWg: &sync.WaitGroup{},
+		stats:    newPipelineStats(),
+		debug:    newDebugOverrides(),
 	}
 }
 
@@ -151,14 +388,40 @@ func (ot *OpenTelemetry) Init(ctx context.Context) []api.Action {
 		return nil
 	}
 
+	// Build the resource shared by all signals
+	res, err := ot.createResource(ctx)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create resource: %v", err))
+	}
+	ot.resource = res
+
+	// Initialize metric exporter first so span processors can record metrics
+	if err := ot.setupMetrics(ctx); err != nil {
+		panic(fmt.Sprintf("failed to setup metrics: %v", err))
+	}
+
+	// Report the pipeline's own health if requested
+	if ot.config.EnableSelfObservability && ot.meterProvider != nil {
+		if err := ot.stats.register(ot.meterProvider); err != nil {
+			panic(fmt.Sprintf("failed to setup self-observability metrics: %v", err))
+		}
+	}
+
+	// Register runtime and host metrics if requested
+	if ot.config.EnableRuntimeMetrics {
+		if err := ot.setupRuntimeMetrics(); err != nil {
+			panic(fmt.Sprintf("failed to setup runtime metrics: %v", err))
+		}
+	}
+
 	// Initialize trace exporter
 	if err := ot.setupTracing(ctx); err != nil {
 		panic(fmt.Sprintf("failed to setup tracing: %v", err))
 	}
 
-	// Initialize metric exporter
-	if err := ot.setupMetrics(ctx); err != nil {
-		panic(fmt.Sprintf("failed to setup metrics: %v", err))
+	// Register context propagators
+	if err := ot.setupPropagators(); err != nil {
+		panic(fmt.Sprintf("failed to setup propagators: %v", err))
 	}
 
 	// Initialize log handler
@@ -166,6 +429,22 @@ func (ot *OpenTelemetry) Init(ctx context.Context) []api.Action {
 		panic(fmt.Sprintf("failed to setup logging: %v", err))
 	}
 
+	// Serve debug overrides if requested
+	if ot.config.EnableAdminEndpoint {
+		if err := ot.startAdminServer(); err != nil {
+			panic(fmt.Sprintf("failed to setup admin endpoint: %v", err))
+		}
+	}
+
+	ot.ready.Store(true)
+
+	// Apply and watch the config file if requested
+	if path := ot.config.ConfigFile; path != "" {
+		watchCtx, cancel := context.WithCancel(context.Background())
+		ot.watchCancel = cancel
+		go ot.watchConfigFile(watchCtx, path)
+	}
+
 	// Set up signal handling for graceful shutdown if a server was started
 	ot.setupSignalHandler()
 
@@ -184,10 +463,119 @@ func (ot *OpenTelemetry) setupTracing(ctx context.Context) error {
 		if err != nil {
 			return err
 		}
+
+		// Reconfigure can point the default exporter at another endpoint
+		ot.traceExporter = &swappableSpanExporter{exporter: spanExporter}
+		spanExporter = ot.traceExporter
 	}
 
-	spanProcessor := trace.NewBatchSpanProcessor(spanExporter)
-	tracing.TracerProvider().RegisterSpanProcessor(spanProcessor)
+	// Without an export queue, spans of failed exports are lost
+	spanExporter = &observedSpanExporter{
+		SpanExporter: spanExporter,
+		stats:        ot.stats,
+		dropFailed:   ot.config.ExportQueueDir == "",
+	}
+
+	// Spool failed batches before transforms run, so replays skip them
+	if dir := ot.config.ExportQueueDir; dir != "" {
+		queued, err := newQueuedSpanExporter(spanExporter, dir, ot.config.ExportQueueMaxBytes)
+		if err != nil {
+			return err
+		}
+		ot.stats.addSpoolSource(signalTraces, queued.queue)
+		spanExporter = queued
+	}
+
+	pricing, err := ot.config.loadPricing()
+	if err != nil {
+		return err
+	}
+	// Processors and transforms share the decoded span inputs and outputs
+	payloads := newSpanPayloads()
+	var transforms []spanTransform
+	if len(pricing) > 0 {
+		transforms = append(transforms, pricing.costTransform(payloads))
+	}
+	if key := ot.config.DocumentIDMetadataKey; key != "" {
+		transforms = append(transforms, documentIDTransform(key, payloads))
+	}
+	// Translate last, as it can remove the genkit:* attributes. Reconfigure
+	// can turn the translation on later.
+	if conventions := ot.config.GenAIConventions; conventions != nil {
+		copied := *conventions
+		ot.genAIConventions.Store(&copied)
+	}
+	transforms = append(transforms, genAITransform(&ot.genAIConventions, ot.debug.active, payloads), payloads.forget)
+	spanExporter = newTransformingSpanExporter(spanExporter, transforms...)
+
+	ot.sampler = newDynamicSampler(ot.config.Sampler)
+	opts := []trace.TracerProviderOption{
+		trace.WithResource(ot.resource),
+		trace.WithSampler(&debugSampler{Sampler: ot.sampler, overrides: ot.debug}),
+	}
+
+	// X-Ray requires the trace ID to start with the epoch time
+	if ot.config.EnableXRay {
+		opts = append(opts, trace.WithIDGenerator(xray.NewIDGenerator()))
+	}
+
+	// Promote allowlisted baggage before any other processor sees the span
+	if len(ot.config.BaggageAttributes) > 0 {
+		opts = append(opts, trace.WithSpanProcessor(newBaggageSpanProcessor(ot.config.BaggageAttributes)))
+	}
+
+	// Attach deployment and request-scoped attributes to every span
+	if len(ot.config.SpanAttributes) > 0 || ot.config.SpanEnricher != nil {
+		opts = append(opts, trace.WithSpanProcessor(newEnrichingSpanProcessor(ot.config.SpanAttributes, ot.config.SpanEnricher)))
+	}
+
+	// Derive Genkit feature, model and tool metrics from spans
+	if ot.meterProvider != nil {
+		genkitMetrics, err := newGenkitMetrics(ot.meterProvider, ot.config.BaggageAttributes, pricing, payloads)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, trace.WithSpanProcessor(genkitMetrics))
+
+		agentTelemetry, err := newAgentTelemetry(ot.meterProvider, ot.config.BaggageAttributes, payloads)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, trace.WithSpanProcessor(agentTelemetry))
+
+		ragMetrics, err := newRAGMetrics(ot.meterProvider, ot.config.BaggageAttributes, payloads)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, trace.WithSpanProcessor(ragMetrics))
+
+		if ot.config.EnableSpanMetrics {
+			spanMetrics, err := newSpanMetrics(ot.meterProvider, ot.config.SpanMetricsDimensions,
+				ot.config.SpanMetricsBuckets, ot.config.SpanMetricsExclude)
+			if err != nil {
+				return err
+			}
+			opts = append(opts, trace.WithSpanProcessor(spanMetrics))
+		}
+	}
+
+	// Filter spans after the metrics above have seen them. Reconfigure can
+	// add filters later.
+	ot.spanFilters, err = newSpanFilterProcessor(newSpanQueueGate(spanExporter, ot.stats), ot.config.SpanFilters, ot.stats)
+	if err != nil {
+		return err
+	}
+	opts = append(opts, trace.WithSpanProcessor(ot.spanFilters))
+
+	// Genkit picks up the global tracer provider, so installing our own
+	// lets us attach the resource and other provider-level options.
+	ot.tracerProvider = trace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(ot.tracerProvider)
+
+	// Keep the Genkit Developer UI receiving traces on the new provider
+	if telemetryURL := os.Getenv("GENKIT_TELEMETRY_SERVER"); telemetryURL != "" {
+		tracing.WriteTelemetryImmediate(tracing.NewHTTPTelemetryClient(telemetryURL))
+	}
 
 	return nil
 }
@@ -204,11 +592,35 @@ func (ot *OpenTelemetry) setupMetrics(ctx context.Context) error {
 
 	if ot.config.MetricExporter != nil {
 		metricExporter = ot.config.MetricExporter
+
+		// Only an explicit setting overrides a custom exporter's own temporality
+		if ot.config.MetricTemporality != "" {
+			selector, err := ot.config.MetricTemporality.selector()
+			if err != nil {
+				return err
+			}
+			metricExporter = &temporalityExporter{Exporter: metricExporter, selector: selector}
+		}
 	} else {
 		metricExporter, err = ot.createDefaultMetricExporter(ctx)
 		if err != nil {
 			return err
 		}
+
+		// Reconfigure can point the default exporter at another endpoint
+		ot.metricExporter = &swappableMetricExporter{exporter: metricExporter}
+		metricExporter = ot.metricExporter
+	}
+
+	metricExporter = &observedMetricExporter{Exporter: metricExporter, stats: ot.stats}
+
+	if dir := ot.config.ExportQueueDir; dir != "" {
+		queued, err := newQueuedMetricExporter(metricExporter, dir, ot.config.ExportQueueMaxBytes)
+		if err != nil {
+			return err
+		}
+		ot.stats.addSpoolSource(signalMetrics, queued.queue)
+		metricExporter = queued
 	}
 
 	reader := metric.NewPeriodicReader(
@@ -216,8 +628,11 @@ func (ot *OpenTelemetry) setupMetrics(ctx context.Context) error {
 		metric.WithInterval(ot.config.MetricInterval),
 	)
 
-	meterProvider := metric.NewMeterProvider(metric.WithReader(reader))
-	otel.SetMeterProvider(meterProvider)
+	ot.meterProvider, err = ot.newMeterProvider(reader)
+	if err != nil {
+		return err
+	}
+	otel.SetMeterProvider(ot.meterProvider)
 
 	return nil
 }
@@ -226,9 +641,13 @@ func (ot *OpenTelemetry) setupMetrics(ctx context.Context) error {
 func (ot *OpenTelemetry) setupLogging() error {
 	var handler slog.Handler
 
+	// Custom handlers filter by their own level until Reconfigure sets one
+	ot.logLevel = &slog.LevelVar{}
 	if ot.config.LogHandler != nil {
-		handler = ot.config.LogHandler
+		ot.logLevel.Set(slog.Level(math.MinInt))
+		handler = &levelHandler{Handler: ot.config.LogHandler, level: ot.logLevel}
 	} else {
+		ot.logLevel.Set(ot.config.LogLevel.Level())
 		handler = ot.createDefaultLogHandler()
 	}
 
@@ -238,13 +657,26 @@ func (ot *OpenTelemetry) setupLogging() error {
 	return nil
 }
 
-// Shutdown gracefully shuts down the OpenTelemetry plugin and any running servers.
-// This method is safe to call multiple times.
+// Shutdown gracefully shuts down the OpenTelemetry plugin and any running
+// servers, then exports the remaining spans and metrics and shuts down the
+// providers. This method is safe to call multiple times.
 func (ot *OpenTelemetry) Shutdown(ctx context.Context) error {
-	var shutdownErr error
+	var errs []error
 
 	ot.shutdownOnce.Do(func() {
 		slog.Info("Shutting down OpenTelemetry plugin...")
+		ot.ready.Store(false)
+
+		// Wait for a Reconfigure in progress and reject later ones, so no
+		// exporter is swapped in after the providers are shut down
+		ot.reconfigureMu.Lock()
+		ot.shutDown = true
+		ot.reconfigureMu.Unlock()
+
+		// Stop watching the config file
+		if ot.watchCancel != nil {
+			ot.watchCancel()
+		}
 
 		// Cancel server context if it exists
 		if ot.serverCancel != nil {
@@ -260,12 +692,16 @@ func (ot *OpenTelemetry) Shutdown(ctx context.Context) error {
 
 			if err := ot.server.Shutdown(shutdownCtx); err != nil {
 				slog.Error("Error shutting down Prometheus metrics server", "error", err)
-				shutdownErr = fmt.Errorf("failed to shutdown Prometheus server: %w", err)
+				errs = append(errs, fmt.Errorf("failed to shutdown Prometheus server: %w", err))
 			} else {
 				slog.Info("Prometheus metrics server shut down successfully")
 			}
 		}
 
+		if err := ot.shutdownAdminServer(ctx); err != nil {
+			errs = append(errs, err)
+		}
+
 		// Wait for server goroutines to finish
 		if ot.serverWg != nil {
 			done := make(chan struct{})
@@ -281,15 +717,45 @@ func (ot *OpenTelemetry) Shutdown(ctx context.Context) error {
 				slog.Warn("Timeout waiting for OpenTelemetry servers to stop")
 			}
 		}
+
+		// Export what is left, spans first so metrics derived from them are
+		// included. Shutting down the exporters also stops the export queue.
+		if ot.tracerProvider != nil {
+			if err := ot.tracerProvider.Shutdown(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("failed to shutdown tracer provider: %w", err))
+			}
+		}
+		if ot.meterProvider != nil {
+			if err := ot.meterProvider.Shutdown(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("failed to shutdown meter provider: %w", err))
+			}
+		}
 	})
 
-	return shutdownErr
+	return errors.Join(errs...)
+}
+
+// ForceFlush exports all spans and metrics recorded so far. Spans are flushed
+// first so metrics derived from them are included.
+func (ot *OpenTelemetry) ForceFlush(ctx context.Context) error {
+	var errs []error
+	if ot.tracerProvider != nil {
+		if err := ot.tracerProvider.ForceFlush(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to flush traces: %w", err))
+		}
+	}
+	if ot.meterProvider != nil {
+		if err := ot.meterProvider.ForceFlush(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to flush metrics: %w", err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // setupSignalHandler sets up signal handling for graceful shutdown.
 // This should be called after Init to ensure proper cleanup when the application terminates.
 func (ot *OpenTelemetry) setupSignalHandler() {
-	if ot.server == nil {
+	if ot.server == nil && ot.adminServer == nil {
 		return // No server to manage
 	}
 
@@ -0,0 +1,131 @@
+package opentelemetry
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OTLPCompression selects how OTLP export payloads are compressed.
+type OTLPCompression string
+
+// Compressions accepted by Config.OTLPCompression, matching the values of
+// OTEL_EXPORTER_OTLP_COMPRESSION.
+const (
+	// CompressionNone sends payloads uncompressed.
+	CompressionNone OTLPCompression = "none"
+
+	// CompressionGzip compresses payloads with gzip.
+	CompressionGzip OTLPCompression = "gzip"
+)
+
+// defaultExportTimeout bounds each OTLP export unless a timeout is configured.
+const defaultExportTimeout = 30 * time.Second
+
+// Signal names, also used in the per-signal OTLP environment variables.
+const (
+	signalTraces  = "traces"
+	signalMetrics = "metrics"
+)
+
+// gzip reports whether c enables compression.
+func (c OTLPCompression) gzip() (bool, error) {
+	switch OTLPCompression(strings.ToLower(string(c))) {
+	case CompressionNone:
+		return false, nil
+	case CompressionGzip:
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown OTLP compression %q", c)
+	}
+}
+
+// RetryConfig is the retry policy for OTLP exports that fail with a
+// retryable error. Retries back off exponentially from InitialInterval to
+// MaxInterval until MaxElapsedTime has passed, after which the batch is
+// dropped (or spooled, see Config.ExportQueueDir).
+type RetryConfig struct {
+	// Disable retries, failing the export on the first error.
+	Disabled bool
+
+	// Delay before the first retry. Defaults to 5s.
+	InitialInterval time.Duration
+
+	// Upper bound of the delay between retries. Defaults to 30s.
+	MaxInterval time.Duration
+
+	// Total time spent on an export, including retries. Defaults to 1m.
+	MaxElapsedTime time.Duration
+}
+
+// withDefaults returns r with unset intervals filled with the exporters'
+// defaults.
+func (r RetryConfig) withDefaults() RetryConfig {
+	if r.InitialInterval == 0 {
+		r.InitialInterval = 5 * time.Second
+	}
+	if r.MaxInterval == 0 {
+		r.MaxInterval = 30 * time.Second
+	}
+	if r.MaxElapsedTime == 0 {
+		r.MaxElapsedTime = time.Minute
+	}
+	return r
+}
+
+// otlpEnv returns the per-signal OTLP environment variable for setting, e.g.
+// OTEL_EXPORTER_OTLP_TRACES_TIMEOUT, falling back to the generic one.
+func otlpEnv(signal, setting string) (name, value string) {
+	name = "OTEL_EXPORTER_OTLP_" + strings.ToUpper(signal) + "_" + setting
+	if value = os.Getenv(name); value != "" {
+		return name, value
+	}
+	name = "OTEL_EXPORTER_OTLP_" + setting
+	return name, os.Getenv(name)
+}
+
+// exportTimeout returns the timeout of the signal's OTLP exports: the
+// configured one, then the standard environment variables in milliseconds,
+// then defaultExportTimeout.
+func (ot *OpenTelemetry) exportTimeout(signal string) time.Duration {
+	configured := ot.config.TraceExportTimeout
+	if signal == signalMetrics {
+		configured = ot.config.MetricExportTimeout
+	}
+	if configured > 0 {
+		return configured
+	}
+
+	// Like the OTLP exporters, ignore invalid environment values
+	if name, value := otlpEnv(signal, "TIMEOUT"); value != "" {
+		ms, err := strconv.Atoi(value)
+		if err == nil && ms > 0 {
+			return time.Duration(ms) * time.Millisecond
+		}
+		slog.Warn("Ignoring invalid OTLP timeout", "env", name, "value", value)
+	}
+	return defaultExportTimeout
+}
+
+// exportCompression reports whether the signal's OTLP exports are
+// compressed: Config.OTLPCompression, then the standard environment
+// variables. Compression is off by default.
+func (ot *OpenTelemetry) exportCompression(signal string) (bool, error) {
+	if ot.config.OTLPCompression != "" {
+		return ot.config.OTLPCompression.gzip()
+	}
+
+	name, value := otlpEnv(signal, "COMPRESSION")
+	if value == "" {
+		return false, nil
+	}
+	gzip, err := OTLPCompression(value).gzip()
+	if err != nil {
+		slog.Warn("Ignoring invalid OTLP compression", "env", name, "error", err)
+		return false, nil
+	}
+	return gzip, nil
+}
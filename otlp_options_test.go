@@ -0,0 +1,108 @@
+package opentelemetry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExportTimeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     Config
+		signal     string
+		signalEnv  string
+		genericEnv string
+		want       time.Duration
+	}{
+		{"default", Config{}, signalTraces, "", "", defaultExportTimeout},
+		{"generic environment", Config{}, signalTraces, "", "2000", 2 * time.Second},
+		{"signal environment over generic", Config{}, signalTraces, "500", "2000", 500 * time.Millisecond},
+		{"config over environment", Config{TraceExportTimeout: time.Second}, signalTraces, "500", "2000", time.Second},
+		{"metrics use their own config", Config{TraceExportTimeout: time.Second, MetricExportTimeout: 3 * time.Second}, signalMetrics, "", "", 3 * time.Second},
+		{"metrics use their own environment", Config{}, signalMetrics, "700", "2000", 700 * time.Millisecond},
+		{"invalid environment is ignored", Config{}, signalTraces, "soon", "", defaultExportTimeout},
+		{"non-positive environment is ignored", Config{}, signalTraces, "", "0", defaultExportTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OTEL_EXPORTER_OTLP_TRACES_TIMEOUT", "")
+			t.Setenv("OTEL_EXPORTER_OTLP_METRICS_TIMEOUT", "")
+			if tt.signal == signalMetrics {
+				t.Setenv("OTEL_EXPORTER_OTLP_METRICS_TIMEOUT", tt.signalEnv)
+			} else {
+				t.Setenv("OTEL_EXPORTER_OTLP_TRACES_TIMEOUT", tt.signalEnv)
+			}
+			t.Setenv("OTEL_EXPORTER_OTLP_TIMEOUT", tt.genericEnv)
+
+			if got := New(tt.config).exportTimeout(tt.signal); got != tt.want {
+				t.Errorf("exportTimeout(%q) = %v, want %v", tt.signal, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportCompression(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     OTLPCompression
+		signalEnv  string
+		genericEnv string
+		want       bool
+		wantErr    bool
+	}{
+		{"default", "", "", "", false, false},
+		{"generic environment", "", "", "gzip", true, false},
+		{"signal environment over generic", "", "none", "gzip", false, false},
+		{"environment in upper case", "", "GZIP", "", true, false},
+		{"config over environment", CompressionNone, "gzip", "gzip", false, false},
+		{"config in mixed case", "Gzip", "", "", true, false},
+		{"invalid environment is ignored", "", "brotli", "", false, false},
+		{"invalid config fails", "brotli", "", "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OTEL_EXPORTER_OTLP_TRACES_COMPRESSION", tt.signalEnv)
+			t.Setenv("OTEL_EXPORTER_OTLP_COMPRESSION", tt.genericEnv)
+
+			got, err := New(Config{OTLPCompression: tt.config}).exportCompression(signalTraces)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("exportCompression() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("exportCompression() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryConfigWithDefaults(t *testing.T) {
+	tests := []struct {
+		name   string
+		config RetryConfig
+		want   RetryConfig
+	}{
+		{"unset", RetryConfig{}, RetryConfig{
+			InitialInterval: 5 * time.Second,
+			MaxInterval:     30 * time.Second,
+			MaxElapsedTime:  time.Minute,
+		}},
+		{"partially set", RetryConfig{MaxInterval: 10 * time.Second}, RetryConfig{
+			InitialInterval: 5 * time.Second,
+			MaxInterval:     10 * time.Second,
+			MaxElapsedTime:  time.Minute,
+		}},
+		{"disabled keeps its flag", RetryConfig{Disabled: true, InitialInterval: time.Second}, RetryConfig{
+			Disabled:        true,
+			InitialInterval: time.Second,
+			MaxInterval:     30 * time.Second,
+			MaxElapsedTime:  time.Minute,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.withDefaults(); got != tt.want {
+				t.Errorf("withDefaults() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
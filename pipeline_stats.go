@@ -0,0 +1,510 @@
+package opentelemetry
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	promclient "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Reasons spans are dropped before export.
+const (
+	dropReasonQueueFull    = "queue_full"
+	dropReasonExportFailed = "export_failed"
+)
+
+// Readers collecting metrics, reported on the collection duration.
+const (
+	readerPeriodic   = "periodic"
+	readerPrometheus = "prometheus"
+)
+
+// Stats are counters of the plugin's own telemetry pipeline, for checking
+// whether it is keeping up and delivering data.
+type Stats struct {
+	// Spans handed to the trace exporter successfully.
+	SpansExported int64
+
+	// Spans lost before export, by reason: "queue_full" when spans ended
+	// faster than they could be exported, "export_failed" when an export
+	// failed and Config.ExportQueueDir is not set.
+	SpansDropped map[string]int64
+
+	// Spans waiting for export, including spans held back by
+	// Config.SpanFilters, and how many can wait before new spans are dropped.
+	SpanQueueSize     int64
+	SpanQueueCapacity int64
+
+	// Trace and metric export stats.
+	Traces  SignalStats
+	Metrics SignalStats
+
+	// Metric collections, by the periodic reader or for Prometheus scrapes,
+	// and the duration of the latest one. Periodic collections are only
+	// timed with Config.EnableSelfObservability.
+	MetricCollections      int64
+	LastCollectionDuration time.Duration
+
+	// Requests served by the Prometheus /metrics endpoint.
+	PrometheusScrapes int64
+}
+
+// SignalStats are the export stats of one signal.
+type SignalStats struct {
+	// Successful exports.
+	Exports int64
+
+	// Failed exports by reason, e.g. "timeout", "unavailable" or "network".
+	Failures map[string]int64
+
+	// Duration of the latest export, including retries.
+	LastExportDuration time.Duration
+
+	// Time of the latest successful export, zero if there was none.
+	LastSuccess time.Time
+
+	// Exports failed in a row since the latest successful one.
+	ConsecutiveFailures int64
+
+	// Batches spooled in the export queue (see Config.ExportQueueDir), and
+	// the fraction of the queue's size limit they use.
+	SpooledBatches  int64
+	ExportQueueFill float64
+}
+
+// pipelineStats collects Stats and reports them as metrics once registered.
+type pipelineStats struct {
+	// Spans ended but not yet handed to the exporter
+	spanQueueSize     atomic.Int64
+	spanQueueCapacity int64
+
+	// Start of the periodic collection in progress, in Unix nanoseconds
+	collectStart atomic.Int64
+
+	mu      sync.Mutex
+	stats   Stats
+	spooled map[string]spoolSource
+
+	instruments atomic.Pointer[statsInstruments]
+}
+
+// statsInstruments are the synchronous instruments of the pipeline metrics.
+type statsInstruments struct {
+	exportDuration     otelmetric.Float64Histogram
+	collectionDuration otelmetric.Float64Histogram
+}
+
+// newPipelineStats creates empty pipeline stats.
+func newPipelineStats() *pipelineStats {
+	return &pipelineStats{
+		stats: Stats{
+			SpansDropped: map[string]int64{},
+			Traces:       SignalStats{Failures: map[string]int64{}},
+			Metrics:      SignalStats{Failures: map[string]int64{}},
+		},
+		spooled: map[string]spoolSource{},
+	}
+}
+
+// Stats returns the current counters of the telemetry pipeline. They are
+// zero until Init has run.
+func (ot *OpenTelemetry) Stats() Stats {
+	return ot.stats.snapshot()
+}
+
+// snapshot returns a copy of the stats.
+func (s *pipelineStats) snapshot() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.stats
+	stats.SpansDropped = maps.Clone(s.stats.SpansDropped)
+	stats.SpanQueueSize = max(s.spanQueueSize.Load(), 0)
+	stats.SpanQueueCapacity = s.spanQueueCapacity
+	stats.Traces = s.signalSnapshot(signalTraces, s.stats.Traces)
+	stats.Metrics = s.signalSnapshot(signalMetrics, s.stats.Metrics)
+	return stats
+}
+
+// signalSnapshot returns a copy of a signal's stats. The caller must hold s.mu.
+func (s *pipelineStats) signalSnapshot(signal string, stats SignalStats) SignalStats {
+	stats.Failures = maps.Clone(stats.Failures)
+	if source, ok := s.spooled[signal]; ok {
+		stats.SpooledBatches = source.spooled()
+		stats.ExportQueueFill = source.fill()
+	}
+	return stats
+}
+
+// signal returns the mutable stats of a signal. The caller must hold s.mu.
+func (s *pipelineStats) signal(signal string) *SignalStats {
+	if signal == signalMetrics {
+		return &s.stats.Metrics
+	}
+	return &s.stats.Traces
+}
+
+// spoolSource is the export queue of a signal.
+type spoolSource interface {
+	spooled() int64
+	fill() float64
+}
+
+// addSpoolSource reports the size of a signal's export queue.
+func (s *pipelineStats) addSpoolSource(signal string, source spoolSource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.spooled[signal] = source
+}
+
+// hasSpoolSource reports whether a signal has an export queue.
+func (s *pipelineStats) hasSpoolSource(signal string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.spooled[signal]
+	return ok
+}
+
+// exported records an export attempt of a signal.
+func (s *pipelineStats) exported(ctx context.Context, signal string, duration time.Duration, err error) {
+	s.mu.Lock()
+	stats := s.signal(signal)
+	stats.LastExportDuration = duration
+	if err == nil {
+		stats.Exports++
+		stats.LastSuccess = time.Now()
+		stats.ConsecutiveFailures = 0
+	} else {
+		stats.Failures[exportFailureReason(err)]++
+		stats.ConsecutiveFailures++
+	}
+	s.mu.Unlock()
+
+	if inst := s.instruments.Load(); inst != nil {
+		outcome := "success"
+		if err != nil {
+			outcome = "failure"
+		}
+		inst.exportDuration.Record(ctx, float64(duration.Nanoseconds())/1e6, otelmetric.WithAttributes(
+			attribute.String("signal", signal),
+			attribute.String("outcome", outcome),
+		))
+	}
+}
+
+// spansExported records spans handed to the exporter.
+func (s *pipelineStats) spansExported(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.SpansExported += int64(n)
+}
+
+// spansDropped records spans lost before export.
+func (s *pipelineStats) spansDropped(reason string, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.SpansDropped[reason] += int64(n)
+}
+
+// collected records a metric collection. A zero duration means it is unknown.
+func (s *pipelineStats) collected(ctx context.Context, reader string, duration time.Duration) {
+	s.mu.Lock()
+	s.stats.MetricCollections++
+	if duration > 0 {
+		s.stats.LastCollectionDuration = duration
+	}
+	s.mu.Unlock()
+
+	if inst := s.instruments.Load(); inst != nil && duration > 0 {
+		inst.collectionDuration.Record(ctx, float64(duration.Nanoseconds())/1e6,
+			otelmetric.WithAttributes(attribute.String("reader", reader)))
+	}
+}
+
+// scraped records a Prometheus scrape.
+func (s *pipelineStats) scraped() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.PrometheusScrapes++
+}
+
+// register reports the stats as genkit_otel.* metrics on mp.
+func (s *pipelineStats) register(mp otelmetric.MeterProvider) error {
+	meter := mp.Meter(instrumentationName)
+
+	exportDuration, err := meter.Float64Histogram(
+		"genkit_otel.export.duration",
+		otelmetric.WithDescription("Duration of trace and metric exports, including retries."),
+		otelmetric.WithUnit("ms"),
+	)
+	if err != nil {
+		return err
+	}
+	collectionDuration, err := meter.Float64Histogram(
+		"genkit_otel.metrics.collection.duration",
+		otelmetric.WithDescription("Duration of metric collections."),
+		otelmetric.WithUnit("ms"),
+	)
+	if err != nil {
+		return err
+	}
+
+	spansExported, err := meter.Int64ObservableCounter(
+		"genkit_otel.spans.exported",
+		otelmetric.WithDescription("Spans handed to the trace exporter successfully."),
+		otelmetric.WithUnit("{span}"),
+	)
+	if err != nil {
+		return err
+	}
+	spansDropped, err := meter.Int64ObservableCounter(
+		"genkit_otel.spans.dropped",
+		otelmetric.WithDescription("Spans lost before export."),
+		otelmetric.WithUnit("{span}"),
+	)
+	if err != nil {
+		return err
+	}
+	spanQueueSize, err := meter.Int64ObservableGauge(
+		"genkit_otel.span_queue.size",
+		otelmetric.WithDescription("Spans waiting for export."),
+		otelmetric.WithUnit("{span}"),
+	)
+	if err != nil {
+		return err
+	}
+	spanQueueCapacity, err := meter.Int64ObservableGauge(
+		"genkit_otel.span_queue.capacity",
+		otelmetric.WithDescription("Spans that can wait for export before new spans are dropped."),
+		otelmetric.WithUnit("{span}"),
+	)
+	if err != nil {
+		return err
+	}
+	exports, err := meter.Int64ObservableCounter(
+		"genkit_otel.exports",
+		otelmetric.WithDescription("Successful trace and metric exports."),
+		otelmetric.WithUnit("{export}"),
+	)
+	if err != nil {
+		return err
+	}
+	exportFailures, err := meter.Int64ObservableCounter(
+		"genkit_otel.export.failures",
+		otelmetric.WithDescription("Failed trace and metric exports."),
+		otelmetric.WithUnit("{export}"),
+	)
+	if err != nil {
+		return err
+	}
+	spooledBatches, err := meter.Int64ObservableGauge(
+		"genkit_otel.export_queue.batches",
+		otelmetric.WithDescription("Batches spooled to disk awaiting export."),
+		otelmetric.WithUnit("{batch}"),
+	)
+	if err != nil {
+		return err
+	}
+	prometheusScrapes, err := meter.Int64ObservableCounter(
+		"genkit_otel.prometheus.scrapes",
+		otelmetric.WithDescription("Requests served by the Prometheus /metrics endpoint."),
+		otelmetric.WithUnit("{scrape}"),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o otelmetric.Observer) error {
+		// Callbacks run first in a collection, so this marks its start
+		s.collectStart.Store(time.Now().UnixNano())
+
+		stats := s.snapshot()
+		o.ObserveInt64(spansExported, stats.SpansExported)
+		for reason, n := range stats.SpansDropped {
+			o.ObserveInt64(spansDropped, n, otelmetric.WithAttributes(attribute.String("reason", reason)))
+		}
+		o.ObserveInt64(spanQueueSize, stats.SpanQueueSize)
+		o.ObserveInt64(spanQueueCapacity, stats.SpanQueueCapacity)
+		for signal, signalStats := range map[string]SignalStats{signalTraces: stats.Traces, signalMetrics: stats.Metrics} {
+			signalAttr := attribute.String("signal", signal)
+			o.ObserveInt64(exports, signalStats.Exports, otelmetric.WithAttributes(signalAttr))
+			for reason, n := range signalStats.Failures {
+				o.ObserveInt64(exportFailures, n, otelmetric.WithAttributes(signalAttr, attribute.String("reason", reason)))
+			}
+			if s.hasSpoolSource(signal) {
+				o.ObserveInt64(spooledBatches, signalStats.SpooledBatches, otelmetric.WithAttributes(signalAttr))
+			}
+		}
+		o.ObserveInt64(prometheusScrapes, stats.PrometheusScrapes)
+		return nil
+	}, spansExported, spansDropped, spanQueueSize, spanQueueCapacity, exports, exportFailures, spooledBatches, prometheusScrapes)
+	if err != nil {
+		return err
+	}
+
+	s.instruments.Store(&statsInstruments{
+		exportDuration:     exportDuration,
+		collectionDuration: collectionDuration,
+	})
+	return nil
+}
+
+// exportFailureReason classifies an export error for the failure counters.
+func exportFailureReason(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "timeout"
+	}
+	if errors.Is(err, context.Canceled) {
+		return "canceled"
+	}
+	if s, ok := status.FromError(err); ok && s.Code() != codes.OK && s.Code() != codes.Unknown {
+		if s.Code() == codes.DeadlineExceeded {
+			return "timeout"
+		}
+		return snakeCase(s.Code().String())
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return "timeout"
+		}
+		return "network"
+	}
+	return "other"
+}
+
+// snakeCase converts a CamelCase name such as "ResourceExhausted" to
+// "resource_exhausted".
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// spanQueueGate admits ended spans to a batch span processor only while its
+// queue has room, counting the spans that would otherwise be dropped
+// silently.
+type spanQueueGate struct {
+	trace.SpanProcessor
+	stats *pipelineStats
+}
+
+// newSpanQueueGate creates a batch span processor exporting to exporter,
+// guarded by a gate. Spans are counted as queued until the exporter receives
+// them, which also covers the batch being assembled, so the processor's own
+// queue never overflows.
+func newSpanQueueGate(exporter trace.SpanExporter, stats *pipelineStats) *spanQueueGate {
+	stats.spanQueueCapacity = trace.DefaultMaxQueueSize
+	return &spanQueueGate{
+		SpanProcessor: trace.NewBatchSpanProcessor(
+			&dequeuingSpanExporter{SpanExporter: exporter, stats: stats},
+			trace.WithMaxQueueSize(trace.DefaultMaxQueueSize),
+		),
+		stats: stats,
+	}
+}
+
+// OnEnd implements trace.SpanProcessor.
+func (g *spanQueueGate) OnEnd(span trace.ReadOnlySpan) {
+	// The batch span processor ignores unsampled spans
+	if !span.SpanContext().IsSampled() {
+		return
+	}
+	if g.stats.spanQueueSize.Add(1) > g.stats.spanQueueCapacity {
+		g.stats.spanQueueSize.Add(-1)
+		g.stats.spansDropped(dropReasonQueueFull, 1)
+		return
+	}
+	g.SpanProcessor.OnEnd(span)
+}
+
+// dequeuingSpanExporter marks spans as no longer queued when the batch span
+// processor hands them to the exporter.
+type dequeuingSpanExporter struct {
+	trace.SpanExporter
+	stats *pipelineStats
+}
+
+// ExportSpans implements trace.SpanExporter.
+func (e *dequeuingSpanExporter) ExportSpans(ctx context.Context, spans []trace.ReadOnlySpan) error {
+	e.stats.spanQueueSize.Add(-int64(len(spans)))
+	return e.SpanExporter.ExportSpans(ctx, spans)
+}
+
+// observedSpanExporter records the outcome of span exports.
+type observedSpanExporter struct {
+	trace.SpanExporter
+	stats *pipelineStats
+
+	// Whether spans of failed exports are lost, i.e. not spooled to disk
+	dropFailed bool
+}
+
+// ExportSpans implements trace.SpanExporter.
+func (e *observedSpanExporter) ExportSpans(ctx context.Context, spans []trace.ReadOnlySpan) error {
+	start := time.Now()
+	err := e.SpanExporter.ExportSpans(ctx, spans)
+	e.stats.exported(ctx, signalTraces, time.Since(start), err)
+	if err == nil {
+		e.stats.spansExported(len(spans))
+	} else if e.dropFailed {
+		e.stats.spansDropped(dropReasonExportFailed, len(spans))
+	}
+	return err
+}
+
+// observedMetricExporter records the outcome of metric exports and the
+// duration of the collections preceding them.
+type observedMetricExporter struct {
+	metric.Exporter
+	stats *pipelineStats
+}
+
+// Export implements metric.Exporter.
+func (e *observedMetricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	// The start of the collection is only known with EnableSelfObservability
+	start := time.Now()
+	var collection time.Duration
+	if collectStart := e.stats.collectStart.Swap(0); collectStart != 0 {
+		collection = start.Sub(time.Unix(0, collectStart))
+	}
+	e.stats.collected(ctx, readerPeriodic, collection)
+
+	err := e.Exporter.Export(ctx, rm)
+	e.stats.exported(ctx, signalMetrics, time.Since(start), err)
+	return err
+}
+
+// observedGatherer records Prometheus scrapes of a gatherer.
+func (s *pipelineStats) observedGatherer(gatherer promclient.Gatherer) promclient.Gatherer {
+	return promclient.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		start := time.Now()
+		families, err := gatherer.Gather()
+		s.scraped()
+		s.collected(context.Background(), readerPrometheus, time.Since(start))
+		return families, err
+	})
+}
@@ -0,0 +1,218 @@
+package opentelemetry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// erroringSpanExporter fails every export with err.
+type erroringSpanExporter struct {
+	err error
+}
+
+// ExportSpans implements trace.SpanExporter.
+func (e *erroringSpanExporter) ExportSpans(context.Context, []trace.ReadOnlySpan) error {
+	return e.err
+}
+
+// Shutdown implements trace.SpanExporter.
+func (e *erroringSpanExporter) Shutdown(context.Context) error { return nil }
+
+// sampledSpans returns n ended, sampled spans.
+func sampledSpans(n int) []trace.ReadOnlySpan {
+	spans := make([]trace.ReadOnlySpan, n)
+	for i := range spans {
+		spans[i] = tracetest.SpanStub{
+			Name: "span",
+			SpanContext: oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+				TraceID:    oteltrace.TraceID{1},
+				SpanID:     oteltrace.SpanID{byte(i + 1)},
+				TraceFlags: oteltrace.FlagsSampled,
+			}),
+		}.Snapshot()
+	}
+	return spans
+}
+
+// int64Points returns the data points of an int64 sum or gauge by the value
+// of one of their attributes, or under "" if they have none.
+func int64Points(t *testing.T, m metricdata.Metrics, key attribute.Key) map[string]int64 {
+	t.Helper()
+	var points []metricdata.DataPoint[int64]
+	switch data := m.Data.(type) {
+	case metricdata.Sum[int64]:
+		points = data.DataPoints
+	case metricdata.Gauge[int64]:
+		points = data.DataPoints
+	default:
+		t.Fatalf("%s = %T, want an int64 sum or gauge", m.Name, m.Data)
+	}
+	values := map[string]int64{}
+	for _, dp := range points {
+		v, _ := dp.Attributes.Value(key)
+		values[v.AsString()] += dp.Value
+	}
+	return values
+}
+
+func TestPipelineStatsFailedExports(t *testing.T) {
+	stats := newPipelineStats()
+	ctx := context.Background()
+
+	failing := &observedSpanExporter{
+		SpanExporter: &erroringSpanExporter{err: status.Error(codes.Unavailable, "collector down")},
+		stats:        stats,
+		dropFailed:   true,
+	}
+	for range 2 {
+		if err := failing.ExportSpans(ctx, sampledSpans(3)); err == nil {
+			t.Fatal("ExportSpans() succeeded, want the exporter's error")
+		}
+	}
+	working := &observedSpanExporter{SpanExporter: tracetest.NewInMemoryExporter(), stats: stats, dropFailed: true}
+	if err := working.ExportSpans(ctx, sampledSpans(4)); err != nil {
+		t.Fatalf("ExportSpans() error = %v", err)
+	}
+	if err := failing.ExportSpans(ctx, sampledSpans(1)); err == nil {
+		t.Fatal("ExportSpans() succeeded, want the exporter's error")
+	}
+
+	got := stats.snapshot()
+	if got.SpansExported != 4 {
+		t.Errorf("SpansExported = %d, want 4", got.SpansExported)
+	}
+	if n := got.SpansDropped[dropReasonExportFailed]; n != 7 {
+		t.Errorf("SpansDropped[%q] = %d, want 7", dropReasonExportFailed, n)
+	}
+	if got.Traces.Exports != 1 {
+		t.Errorf("Traces.Exports = %d, want 1", got.Traces.Exports)
+	}
+	if n := got.Traces.Failures["unavailable"]; n != 3 {
+		t.Errorf("Traces.Failures = %v, want 3 unavailable", got.Traces.Failures)
+	}
+	if got.Traces.ConsecutiveFailures != 1 {
+		t.Errorf("Traces.ConsecutiveFailures = %d, want 1", got.Traces.ConsecutiveFailures)
+	}
+	if got.Traces.LastSuccess.IsZero() {
+		t.Error("Traces.LastSuccess is zero after a successful export")
+	}
+
+	// Snapshots are copies
+	got.SpansDropped[dropReasonExportFailed] = 0
+	got.Traces.Failures["unavailable"] = 0
+	if again := stats.snapshot(); again.SpansDropped[dropReasonExportFailed] != 7 || again.Traces.Failures["unavailable"] != 3 {
+		t.Error("modifying a snapshot changed the stats")
+	}
+
+	// With an export queue, failed spans are kept rather than dropped
+	queued := &observedSpanExporter{SpanExporter: failing.SpanExporter, stats: stats}
+	_ = queued.ExportSpans(ctx, sampledSpans(5))
+	if n := stats.snapshot().SpansDropped[dropReasonExportFailed]; n != 7 {
+		t.Errorf("SpansDropped[%q] = %d with an export queue, want still 7", dropReasonExportFailed, n)
+	}
+}
+
+func TestPipelineStatsQueue(t *testing.T) {
+	stats := newPipelineStats()
+	gate := &spanQueueGate{SpanProcessor: trace.NewSimpleSpanProcessor(tracetest.NewNoopExporter()), stats: stats}
+	stats.spanQueueCapacity = 2
+
+	// The simple processor exports synchronously without dequeuing, so the
+	// ended spans stay counted as queued
+	for _, span := range sampledSpans(3) {
+		gate.OnEnd(span)
+	}
+	got := stats.snapshot()
+	if got.SpanQueueSize != 2 || got.SpanQueueCapacity != 2 {
+		t.Errorf("span queue = %d/%d, want 2/2", got.SpanQueueSize, got.SpanQueueCapacity)
+	}
+	if n := got.SpansDropped[dropReasonQueueFull]; n != 1 {
+		t.Errorf("SpansDropped[%q] = %d, want 1", dropReasonQueueFull, n)
+	}
+}
+
+func TestPipelineStatsMetrics(t *testing.T) {
+	stats := newPipelineStats()
+	reader := metric.NewManualReader()
+	provider := metric.NewMeterProvider(metric.WithReader(reader))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	if err := stats.register(provider); err != nil {
+		t.Fatalf("register() error = %v", err)
+	}
+
+	ctx := context.Background()
+	exporter := &observedSpanExporter{
+		SpanExporter: &erroringSpanExporter{err: context.DeadlineExceeded},
+		stats:        stats,
+		dropFailed:   true,
+	}
+	_ = exporter.ExportSpans(ctx, sampledSpans(2))
+	stats.exported(ctx, signalMetrics, time.Millisecond, nil)
+	stats.spansExported(6)
+	stats.spanQueueCapacity = 10
+	stats.spanQueueSize.Store(3)
+
+	if got := int64Points(t, collectMetric(t, reader, "genkit_otel.spans.exported"), ""); got[""] != 6 {
+		t.Errorf("genkit_otel.spans.exported = %v, want 6", got)
+	}
+	if got := int64Points(t, collectMetric(t, reader, "genkit_otel.spans.dropped"), "reason"); got[dropReasonExportFailed] != 2 {
+		t.Errorf("genkit_otel.spans.dropped = %v, want 2 export_failed", got)
+	}
+	if got := int64Points(t, collectMetric(t, reader, "genkit_otel.span_queue.size"), ""); got[""] != 3 {
+		t.Errorf("genkit_otel.span_queue.size = %v, want 3", got)
+	}
+	if got := int64Points(t, collectMetric(t, reader, "genkit_otel.span_queue.capacity"), ""); got[""] != 10 {
+		t.Errorf("genkit_otel.span_queue.capacity = %v, want 10", got)
+	}
+	if got := int64Points(t, collectMetric(t, reader, "genkit_otel.exports"), "signal"); got[signalMetrics] != 1 || got[signalTraces] != 0 {
+		t.Errorf("genkit_otel.exports = %v, want 1 for metrics only", got)
+	}
+	if got := int64Points(t, collectMetric(t, reader, "genkit_otel.export.failures"), "reason"); got["timeout"] != 1 {
+		t.Errorf("genkit_otel.export.failures = %v, want 1 timeout", got)
+	}
+
+	// Without an export queue the spooled batches gauge is not reported
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatal(err)
+	}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == "genkit_otel.export_queue.batches" {
+				if points := int64Points(t, m, "signal"); len(points) != 0 {
+					t.Errorf("genkit_otel.export_queue.batches = %v without an export queue", points)
+				}
+			}
+		}
+	}
+}
+
+func TestExportFailureReason(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{context.DeadlineExceeded, "timeout"},
+		{context.Canceled, "canceled"},
+		{status.Error(codes.DeadlineExceeded, "slow"), "timeout"},
+		{status.Error(codes.ResourceExhausted, "quota"), "resource_exhausted"},
+		{status.Error(codes.Unknown, "?"), "other"},
+		{errors.New("boom"), "other"},
+	}
+	for _, tt := range tests {
+		if got := exportFailureReason(tt.err); got != tt.want {
+			t.Errorf("exportFailureReason(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
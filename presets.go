@@ -30,9 +30,22 @@ const (
 
 	// PresetOTLP configures for standard OTLP (default)
 	PresetOTLP PresetType = "otlp"
+
+	// PresetGoogleCloud configures for Google Cloud Trace and Cloud Monitoring
+	// through the Google Cloud OTLP endpoint
+	PresetGoogleCloud PresetType = "googlecloud"
+
+	// PresetAWS configures for AWS X-Ray through the AWS Distro for
+	// OpenTelemetry (ADOT) collector
+	PresetAWS PresetType = "aws"
 )
 
+// googleCloudOTLPEndpoint is the Google Cloud Telemetry API OTLP endpoint.
+const googleCloudOTLPEndpoint = "https://telemetry.googleapis.com:443"
+
 // NewWithPreset creates a new OpenTelemetry plugin with a preset configuration.
+// Fields set in customConfig replace the preset's values, except for maps
+// such as OTLPHeaders, which are merged key by key.
 func NewWithPreset(preset PresetType, customConfig ...Config) *OpenTelemetry {
 	config := createPresetConfig(preset)
 
@@ -47,6 +60,8 @@ func NewWithPreset(preset PresetType, customConfig ...Config) *OpenTelemetry {
 		config:     config,
 		presetType: &preset,
 		serverWg:   &sync.WaitGroup{},
+		stats:      newPipelineStats(),
+		debug:      newDebugOverrides(),
 	}
 }
 
@@ -82,6 +97,26 @@ func createPresetConfig(preset PresetType) Config {
 			MetricExporter: createStdoutMetricExporter(),
 		}
 
+	case PresetGoogleCloud:
+		return Config{
+			OTLPEndpoint:         googleCloudOTLPEndpoint,
+			OTLPUseHTTP:          false, // ADC credentials are attached to gRPC and HTTP requests
+			ServiceName:          "genkit-service",
+			MetricInterval:       60 * time.Second, // Cloud Monitoring accepts one point per minute at most
+			LogLevel:             slog.LevelInfo,
+			GoogleCloudProjectID: googleCloudProjectID(),
+		}
+
+	case PresetAWS:
+		return Config{
+			OTLPEndpoint:   "localhost:4317", // ADOT collector OTLP gRPC receiver
+			OTLPUseHTTP:    false,
+			ServiceName:    "genkit-service",
+			MetricInterval: 60 * time.Second,
+			LogLevel:       slog.LevelInfo,
+			EnableXRay:     true,
+		}
+
 	case PresetOTLP:
 		fallthrough
 	default:
@@ -95,7 +130,9 @@ func createPresetConfig(preset PresetType) Config {
 	}
 }
 
-// mergeConfig merges custom config into the base config.
+// mergeConfig merges custom config into the base config. Fields set in custom,
+// including slices such as MetricViews and SpanFilters, replace the base
+// value; maps such as OTLPHeaders and SpanAttributes are merged key by key.
 func mergeConfig(base *Config, custom Config) {
 	if custom.ForceExport {
 		base.ForceExport = custom.ForceExport
@@ -152,4 +189,128 @@ func mergeConfig(base *Config, custom Config) {
 	if custom.EnablePrometheusExporter {
 		base.EnablePrometheusExporter = custom.EnablePrometheusExporter
 	}
+	if custom.GoogleCloudProjectID != "" {
+		base.GoogleCloudProjectID = custom.GoogleCloudProjectID
+	}
+	if custom.EnableXRay {
+		base.EnableXRay = custom.EnableXRay
+	}
+	if custom.Propagators != nil {
+		base.Propagators = custom.Propagators
+	}
+	if custom.BaggageAttributes != nil {
+		base.BaggageAttributes = custom.BaggageAttributes
+	}
+	if custom.Pricing != nil {
+		base.Pricing = custom.Pricing
+	}
+	if custom.PricingFile != "" {
+		base.PricingFile = custom.PricingFile
+	}
+	if custom.MetricViews != nil {
+		base.MetricViews = custom.MetricViews
+	}
+	if custom.MetricCardinalityLimit != 0 {
+		base.MetricCardinalityLimit = custom.MetricCardinalityLimit
+	}
+	if custom.ExponentialHistograms {
+		base.ExponentialHistograms = custom.ExponentialHistograms
+	}
+	if custom.MetricTemporality != "" {
+		base.MetricTemporality = custom.MetricTemporality
+	}
+	if custom.EnableRuntimeMetrics {
+		base.EnableRuntimeMetrics = custom.EnableRuntimeMetrics
+	}
+	if custom.StreamChunkEvents != 0 {
+		base.StreamChunkEvents = custom.StreamChunkEvents
+	}
+	if custom.DocumentIDMetadataKey != "" {
+		base.DocumentIDMetadataKey = custom.DocumentIDMetadataKey
+	}
+	if custom.EnableSpanMetrics {
+		base.EnableSpanMetrics = custom.EnableSpanMetrics
+	}
+	if custom.SpanMetricsDimensions != nil {
+		base.SpanMetricsDimensions = custom.SpanMetricsDimensions
+	}
+	if custom.SpanMetricsBuckets != nil {
+		base.SpanMetricsBuckets = custom.SpanMetricsBuckets
+	}
+	if custom.SpanMetricsExclude != nil {
+		base.SpanMetricsExclude = custom.SpanMetricsExclude
+	}
+	if custom.ExemplarFilter != "" {
+		base.ExemplarFilter = custom.ExemplarFilter
+	}
+	if custom.ExportQueueDir != "" {
+		base.ExportQueueDir = custom.ExportQueueDir
+	}
+	if custom.ExportQueueMaxBytes != 0 {
+		base.ExportQueueMaxBytes = custom.ExportQueueMaxBytes
+	}
+	if custom.TraceExportTimeout != 0 {
+		base.TraceExportTimeout = custom.TraceExportTimeout
+	}
+	if custom.MetricExportTimeout != 0 {
+		base.MetricExportTimeout = custom.MetricExportTimeout
+	}
+	if custom.OTLPCompression != "" {
+		base.OTLPCompression = custom.OTLPCompression
+	}
+	if custom.OTLPRetry != nil {
+		base.OTLPRetry = custom.OTLPRetry
+	}
+	if custom.EnableSelfObservability {
+		base.EnableSelfObservability = custom.EnableSelfObservability
+	}
+	if custom.EnableHealthEndpoint {
+		base.EnableHealthEndpoint = custom.EnableHealthEndpoint
+	}
+	if custom.HealthFailureThreshold != 0 {
+		base.HealthFailureThreshold = custom.HealthFailureThreshold
+	}
+	if custom.HealthQueueThreshold != 0 {
+		base.HealthQueueThreshold = custom.HealthQueueThreshold
+	}
+	if custom.Sampler != nil {
+		base.Sampler = custom.Sampler
+	}
+	if custom.ConfigFile != "" {
+		base.ConfigFile = custom.ConfigFile
+	}
+	if custom.EnableAdminEndpoint {
+		base.EnableAdminEndpoint = true
+	}
+	if custom.AdminAddr != "" {
+		base.AdminAddr = custom.AdminAddr
+	}
+	if custom.AdminToken != "" {
+		base.AdminToken = custom.AdminToken
+	}
+	if len(custom.SpanFilters) > 0 {
+		base.SpanFilters = custom.SpanFilters
+	}
+	if custom.SpanAttributes != nil {
+		if base.SpanAttributes == nil {
+			base.SpanAttributes = make(map[string]string)
+		}
+		for k, v := range custom.SpanAttributes {
+			base.SpanAttributes[k] = v
+		}
+	}
+	if custom.SpanEnricher != nil {
+		base.SpanEnricher = custom.SpanEnricher
+	}
+	if custom.GenAIConventions != nil {
+		base.GenAIConventions = custom.GenAIConventions
+	}
+	if custom.HistogramBuckets != nil {
+		if base.HistogramBuckets == nil {
+			base.HistogramBuckets = make(map[string][]float64)
+		}
+		for k, v := range custom.HistogramBuckets {
+			base.HistogramBuckets[k] = v
+		}
+	}
 }
@@ -0,0 +1,54 @@
+package opentelemetry
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/otel/sdk/metric"
+)
+
+func TestMergeConfig(t *testing.T) {
+	presetView := metric.NewView(metric.Instrument{Name: "preset"}, metric.Stream{Name: "preset.renamed"})
+	customView := metric.NewView(metric.Instrument{Name: "custom"}, metric.Stream{Name: "custom.renamed"})
+	base := Config{
+		MetricViews:    []metric.View{presetView},
+		SpanFilters:    []SpanFilter{{Type: "util"}},
+		SpanAttributes: map[string]string{"team": "search", "env": "dev"},
+		Propagators:    []string{PropagatorXRay},
+		OTLPHeaders:    map[string]string{"a": "preset", "b": "preset"},
+	}
+	mergeConfig(&base, Config{
+		MetricViews:    []metric.View{customView},
+		SpanFilters:    []SpanFilter{{Type: "flowStep"}},
+		SpanAttributes: map[string]string{"env": "prod"},
+		Propagators:    []string{PropagatorB3},
+		OTLPHeaders:    map[string]string{"b": "custom"},
+	})
+
+	// Slices replace the preset's values
+	if len(base.MetricViews) != 1 {
+		t.Fatalf("MetricViews has %d views, want only the custom one", len(base.MetricViews))
+	}
+	if stream, ok := base.MetricViews[0](metric.Instrument{Name: "custom"}); !ok || stream.Name != "custom.renamed" {
+		t.Errorf("MetricViews[0] = %+v, %v, want the custom view", stream, ok)
+	}
+	if len(base.SpanFilters) != 1 || base.SpanFilters[0].Type != "flowStep" {
+		t.Errorf("SpanFilters = %+v, want only the custom filter", base.SpanFilters)
+	}
+	if len(base.Propagators) != 1 || base.Propagators[0] != PropagatorB3 {
+		t.Errorf("Propagators = %v, want [b3]", base.Propagators)
+	}
+
+	// Maps are merged key by key
+	if base.OTLPHeaders["a"] != "preset" || base.OTLPHeaders["b"] != "custom" {
+		t.Errorf("OTLPHeaders = %v, want a from the preset and b from the custom config", base.OTLPHeaders)
+	}
+	if base.SpanAttributes["team"] != "search" || base.SpanAttributes["env"] != "prod" {
+		t.Errorf("SpanAttributes = %v, want team from the preset and env from the custom config", base.SpanAttributes)
+	}
+
+	// Unset fields keep the preset's values
+	mergeConfig(&base, Config{})
+	if len(base.MetricViews) != 1 || len(base.SpanFilters) != 1 {
+		t.Errorf("empty config changed MetricViews %d or SpanFilters %d", len(base.MetricViews), len(base.SpanFilters))
+	}
+}
@@ -0,0 +1,125 @@
+package opentelemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/firebase/genkit/go/ai"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+// genAIUsageCostAttr is the span attribute holding the cost of a model call.
+const genAIUsageCostAttr = "gen_ai.usage.cost"
+
+// ModelPrice is the price of a model in USD per million tokens.
+type ModelPrice struct {
+	// Price per million input tokens.
+	Input float64 `json:"input"`
+
+	// Price per million output tokens, including thinking tokens.
+	Output float64 `json:"output"`
+
+	// Price per million cached input tokens. Defaults to the input price.
+	CachedInput float64 `json:"cachedInput,omitempty"`
+}
+
+// PricingTable maps model names to their prices. Keys may include the
+// provider prefix ("googleai/gemini-2.5-flash") or omit it ("gemini-2.5-flash").
+type PricingTable map[string]ModelPrice
+
+// LoadPricingTable reads a pricing table from a JSON file of the form
+//
+//	{"googleai/gemini-2.5-flash": {"input": 0.30, "output": 2.50, "cachedInput": 0.075}}
+func LoadPricingTable(path string) (PricingTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pricing table: %w", err)
+	}
+	var table PricingTable
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("failed to parse pricing table %s: %w", path, err)
+	}
+	return table, nil
+}
+
+// Lookup returns the price for a model, first by its full name and then by the
+// name without the provider prefix.
+func (t PricingTable) Lookup(model string) (ModelPrice, bool) {
+	if price, ok := t[model]; ok {
+		return price, true
+	}
+	if _, name, found := strings.Cut(model, "/"); found {
+		price, ok := t[name]
+		return price, ok
+	}
+	return ModelPrice{}, false
+}
+
+// Cost returns the cost in USD of a model call with the given usage.
+func (p ModelPrice) Cost(usage *ai.GenerationUsage) float64 {
+	if usage == nil {
+		return 0
+	}
+
+	cachedPrice := p.CachedInput
+	if cachedPrice == 0 {
+		cachedPrice = p.Input
+	}
+	uncached := max(usage.InputTokens-usage.CachedContentTokens, 0)
+	output := usage.OutputTokens + usage.ThoughtsTokens
+
+	return (float64(uncached)*p.Input +
+		float64(usage.CachedContentTokens)*cachedPrice +
+		float64(output)*p.Output) / 1e6
+}
+
+// modelCost returns the cost of the model call recorded on a span.
+func (t PricingTable) modelCost(span trace.ReadOnlySpan, payloads *spanPayloads) (float64, bool) {
+	attrs := span.Attributes()
+	if len(t) == 0 || stringAttribute(attrs, genkitSubtypeAttr) != "model" {
+		return 0, false
+	}
+	price, ok := t.Lookup(stringAttribute(attrs, genkitNameAttr))
+	if !ok {
+		return 0, false
+	}
+	output := modelResponse(payloads, span)
+	if output.Usage == nil {
+		return 0, false
+	}
+	return price.Cost(output.Usage), true
+}
+
+// costTransform returns a span transform that adds the gen_ai.usage.cost
+// attribute to model spans.
+func (t PricingTable) costTransform(payloads *spanPayloads) spanTransform {
+	return func(span trace.ReadOnlySpan) trace.ReadOnlySpan {
+		cost, ok := t.modelCost(span, payloads)
+		if !ok {
+			return span
+		}
+		return withAttributes(span, attribute.Float64(genAIUsageCostAttr, cost))
+	}
+}
+
+// loadPricing combines the pricing file and inline pricing from the config,
+// with inline entries taking precedence.
+func (c *Config) loadPricing() (PricingTable, error) {
+	table := PricingTable{}
+	if c.PricingFile != "" {
+		loaded, err := LoadPricingTable(c.PricingFile)
+		if err != nil {
+			return nil, err
+		}
+		for model, price := range loaded {
+			table[model] = price
+		}
+	}
+	for model, price := range c.Pricing {
+		table[model] = price
+	}
+	return table, nil
+}
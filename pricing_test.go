@@ -0,0 +1,107 @@
+package opentelemetry_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/firebase/genkit/go/ai"
+	opentelemetry "github.com/xavidop/genkit-opentelemetry-go"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestModelPriceCost(t *testing.T) {
+	tests := []struct {
+		name  string
+		price opentelemetry.ModelPrice
+		usage *ai.GenerationUsage
+		want  float64
+	}{
+		{"no usage", opentelemetry.ModelPrice{Input: 1, Output: 2}, nil, 0},
+		{"input and output", opentelemetry.ModelPrice{Input: 1, Output: 2}, &ai.GenerationUsage{InputTokens: 1000, OutputTokens: 500}, 0.002},
+		{"thinking billed as output", opentelemetry.ModelPrice{Input: 1, Output: 2}, &ai.GenerationUsage{OutputTokens: 500, ThoughtsTokens: 500}, 0.002},
+		{"cached input", opentelemetry.ModelPrice{Input: 1, Output: 2, CachedInput: 0.5}, &ai.GenerationUsage{InputTokens: 1000, CachedContentTokens: 400}, 0.0008},
+		{"cached input at input price", opentelemetry.ModelPrice{Input: 1}, &ai.GenerationUsage{InputTokens: 1000, CachedContentTokens: 400}, 0.001},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.price.Cost(tt.usage); got != tt.want {
+				t.Errorf("Cost() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPricingTableLookup(t *testing.T) {
+	table := opentelemetry.PricingTable{
+		"googleai/gemini-2.5-flash": {Input: 1},
+		"gpt-4o":                    {Input: 2},
+	}
+	tests := []struct {
+		model  string
+		want   float64
+		wantOK bool
+	}{
+		{"googleai/gemini-2.5-flash", 1, true},
+		{"openai/gpt-4o", 2, true},
+		{"gpt-4o", 2, true},
+		{"vertexai/gemini-2.5-flash", 0, false},
+	}
+	for _, tt := range tests {
+		price, ok := table.Lookup(tt.model)
+		if ok != tt.wantOK || price.Input != tt.want {
+			t.Errorf("Lookup(%q) = %v, %v, want input price %v, %v", tt.model, price, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestLoadPricingTable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pricing.json")
+	if err := os.WriteFile(path, []byte(`{"test/model": {"input": 0.3, "output": 2.5, "cachedInput": 0.075}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	table, err := opentelemetry.LoadPricingTable(path)
+	if err != nil {
+		t.Fatalf("LoadPricingTable() error = %v", err)
+	}
+	if got, want := table["test/model"], (opentelemetry.ModelPrice{Input: 0.3, Output: 2.5, CachedInput: 0.075}); got != want {
+		t.Errorf("LoadPricingTable() = %v, want %v", got, want)
+	}
+
+	if err := os.WriteFile(path, []byte(`{`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := opentelemetry.LoadPricingTable(path); err == nil {
+		t.Error("LoadPricingTable() of invalid JSON succeeded")
+	}
+}
+
+func TestCostOfModelCalls(t *testing.T) {
+	tel, g := newTestGenkit(t, opentelemetry.Config{
+		Pricing: opentelemetry.PricingTable{"model": {Input: 1, Output: 2}},
+	})
+	runFlow(t, defineGenerateFlow(g, "costFlow"), "hi")
+
+	tel.AssertSpan(testModel, attribute.Float64("gen_ai.usage.cost", 0.002))
+	point := tel.AssertMetric("genkit/ai/generate/cost", 0.002, attribute.String("modelName", testModel))
+	if got, _ := point.Attributes.Value("featureName"); got.AsString() != "costFlow" {
+		t.Errorf("featureName = %q, want costFlow", got.AsString())
+	}
+}
+
+func TestCostWithoutPrice(t *testing.T) {
+	tel, g := newTestGenkit(t, opentelemetry.Config{
+		Pricing: opentelemetry.PricingTable{"other-model": {Input: 1}},
+	})
+	runFlow(t, defineGenerateFlow(g, "unpricedFlow"), "hi")
+
+	span := tel.AssertSpan(testModel)
+	if hasAttribute(span, "gen_ai.usage.cost") {
+		t.Error("model span without a price has gen_ai.usage.cost")
+	}
+	for _, point := range tel.Metrics.Points() {
+		if point.Name == "genkit/ai/generate/cost" {
+			t.Errorf("unexpected cost data point %v", point)
+		}
+	}
+}
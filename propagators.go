@@ -0,0 +1,107 @@
+package opentelemetry
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/contrib/propagators/aws/xray"
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/contrib/propagators/jaeger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Propagator names accepted by Config.Propagators and OTEL_PROPAGATORS.
+const (
+	PropagatorTraceContext = "tracecontext"
+	PropagatorBaggage      = "baggage"
+	PropagatorB3           = "b3"
+	PropagatorB3Multi      = "b3multi"
+	PropagatorJaeger       = "jaeger"
+	PropagatorXRay         = "xray"
+	PropagatorNone         = "none"
+)
+
+// defaultPropagators are used when neither Config.Propagators nor
+// OTEL_PROPAGATORS is set.
+var defaultPropagators = []string{PropagatorTraceContext, PropagatorBaggage}
+
+// setupPropagators registers the global text map propagator used to continue
+// incoming traces and propagate context to downstream calls.
+func (ot *OpenTelemetry) setupPropagators() error {
+	configured := ot.config.Propagators
+	if len(configured) == 0 {
+		if env := os.Getenv("OTEL_PROPAGATORS"); env != "" {
+			configured = strings.Split(env, ",")
+		} else {
+			configured = defaultPropagators
+		}
+	}
+	names, err := normalizePropagators(configured)
+	if err != nil {
+		return err
+	}
+
+	// X-Ray needs its own header to join traces started by ALB and API
+	// Gateway, unless propagation was disabled explicitly
+	if ot.config.EnableXRay {
+		if slices.Equal(names, []string{PropagatorNone}) {
+			slog.Warn("Propagators set to none, not propagating the X-Ray trace header")
+		} else if !slices.Contains(names, PropagatorXRay) {
+			names = append(names, PropagatorXRay)
+		}
+	}
+
+	propagator, err := newPropagator(names)
+	if err != nil {
+		return err
+	}
+	otel.SetTextMapPropagator(propagator)
+
+	return nil
+}
+
+// normalizePropagators trims and lowercases propagator names, dropping empty
+// and repeated ones. "none" cannot be combined with other propagators.
+func normalizePropagators(configured []string) ([]string, error) {
+	var names []string
+	for _, name := range configured {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) > 1 && slices.Contains(names, PropagatorNone) {
+		return nil, fmt.Errorf("propagator %q cannot be combined with others: %v", PropagatorNone, names)
+	}
+	return names, nil
+}
+
+// newPropagator builds a composite propagator from normalized names. "none"
+// yields a propagator that does nothing.
+func newPropagator(names []string) (propagation.TextMapPropagator, error) {
+	var propagators []propagation.TextMapPropagator
+	for _, name := range names {
+		switch name {
+		case PropagatorTraceContext:
+			propagators = append(propagators, propagation.TraceContext{})
+		case PropagatorBaggage:
+			propagators = append(propagators, propagation.Baggage{})
+		case PropagatorB3:
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3SingleHeader)))
+		case PropagatorB3Multi:
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+		case PropagatorJaeger:
+			propagators = append(propagators, jaeger.Jaeger{})
+		case PropagatorXRay:
+			propagators = append(propagators, xray.Propagator{})
+		case PropagatorNone:
+		default:
+			return nil, fmt.Errorf("unknown propagator %q", name)
+		}
+	}
+	return propagation.NewCompositeTextMapPropagator(propagators...), nil
+}
@@ -0,0 +1,151 @@
+package opentelemetry
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// sampledContext returns a context carrying a sampled remote span and a
+// baggage member.
+func sampledContext(t *testing.T) context.Context {
+	t.Helper()
+	sc := oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    oteltrace.TraceID{0x5f, 0x1, 0x2, 0x3, 0x4, 0x5, 0x6, 0x7, 0x8, 0x9, 0xa, 0xb, 0xc, 0xd, 0xe, 0xf},
+		SpanID:     oteltrace.SpanID{0x1, 0x2, 0x3, 0x4, 0x5, 0x6, 0x7, 0x8},
+		TraceFlags: oteltrace.FlagsSampled,
+		Remote:     true,
+	})
+	member, err := baggage.NewMember("tenant", "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bag, err := baggage.New(member)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return baggage.ContextWithBaggage(oteltrace.ContextWithRemoteSpanContext(context.Background(), sc), bag)
+}
+
+// headerKeys returns the sorted keys of header.
+func headerKeys(header http.Header) []string {
+	return slices.Sorted(maps.Keys(header))
+}
+
+func TestNewPropagator(t *testing.T) {
+	tests := []struct {
+		names       []string
+		wantHeaders []string
+		wantBaggage bool
+	}{
+		{[]string{PropagatorTraceContext}, []string{"Traceparent"}, false},
+		{[]string{PropagatorTraceContext, PropagatorBaggage}, []string{"Baggage", "Traceparent"}, true},
+		{[]string{PropagatorB3}, []string{"B3"}, false},
+		{[]string{PropagatorB3Multi}, []string{"X-B3-Sampled", "X-B3-Spanid", "X-B3-Traceid"}, false},
+		{[]string{PropagatorJaeger}, []string{"Uber-Trace-Id"}, false},
+		{[]string{PropagatorXRay}, []string{"X-Amzn-Trace-Id"}, false},
+		{[]string{PropagatorNone}, nil, false},
+	}
+	for _, tt := range tests {
+		propagator, err := newPropagator(tt.names)
+		if err != nil {
+			t.Fatalf("newPropagator(%v) error = %v", tt.names, err)
+		}
+
+		ctx := sampledContext(t)
+		header := http.Header{}
+		propagator.Inject(ctx, propagation.HeaderCarrier(header))
+		if got := headerKeys(header); !slices.Equal(got, tt.wantHeaders) {
+			t.Errorf("newPropagator(%v) injected %v, want %v", tt.names, got, tt.wantHeaders)
+		}
+
+		extracted := propagator.Extract(context.Background(), propagation.HeaderCarrier(header))
+		want := oteltrace.SpanContextFromContext(ctx)
+		sc := oteltrace.SpanContextFromContext(extracted)
+		if len(tt.wantHeaders) == 0 {
+			if sc.IsValid() {
+				t.Errorf("newPropagator(%v) extracted %v, want nothing", tt.names, sc)
+			}
+			continue
+		}
+		if sc.TraceID() != want.TraceID() || sc.SpanID() != want.SpanID() || !sc.IsSampled() {
+			t.Errorf("newPropagator(%v) extracted %v, want %v", tt.names, sc, want)
+		}
+		if got := baggage.FromContext(extracted).Member("tenant").Value(); (got == "acme") != tt.wantBaggage {
+			t.Errorf("newPropagator(%v) extracted baggage %q, want it only with the baggage propagator", tt.names, got)
+		}
+	}
+}
+
+func TestNewPropagatorUnknownName(t *testing.T) {
+	if _, err := newPropagator([]string{PropagatorTraceContext, "ottrace"}); err == nil {
+		t.Error("newPropagator() with an unknown name succeeded")
+	}
+}
+
+func TestNormalizePropagators(t *testing.T) {
+	tests := []struct {
+		configured []string
+		want       []string
+		wantErr    bool
+	}{
+		{[]string{" TraceContext ", "baggage", "", "tracecontext"}, []string{"tracecontext", "baggage"}, false},
+		{[]string{"B3Multi"}, []string{"b3multi"}, false},
+		{[]string{"none", "NONE"}, []string{"none"}, false},
+		{[]string{"none", "b3"}, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := normalizePropagators(tt.configured)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("normalizePropagators(%q) error = %v, wantErr %v", tt.configured, err, tt.wantErr)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("normalizePropagators(%q) = %v, want %v", tt.configured, got, tt.want)
+		}
+	}
+}
+
+func TestSetupPropagators(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    string
+		config Config
+		want   []string
+	}{
+		{"default", "", Config{}, []string{"Baggage", "Traceparent"}},
+		{"environment", "b3, jaeger", Config{}, []string{"B3", "Uber-Trace-Id"}},
+		{"config over environment", "b3", Config{Propagators: []string{"b3multi"}}, []string{"X-B3-Sampled", "X-B3-Spanid", "X-B3-Traceid"}},
+		{"X-Ray added", "", Config{EnableXRay: true}, []string{"Baggage", "Traceparent", "X-Amzn-Trace-Id"}},
+		{"X-Ray not added to none", "none", Config{EnableXRay: true}, nil},
+	}
+	previous := otel.GetTextMapPropagator()
+	defer otel.SetTextMapPropagator(previous)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OTEL_PROPAGATORS", tt.env)
+			ot := New(tt.config)
+			if err := ot.setupPropagators(); err != nil {
+				t.Fatalf("setupPropagators() error = %v", err)
+			}
+
+			header := http.Header{}
+			otel.GetTextMapPropagator().Inject(sampledContext(t), propagation.HeaderCarrier(header))
+			if got := headerKeys(header); !slices.Equal(got, tt.want) {
+				t.Errorf("injected %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	ot := New(Config{Propagators: []string{"carrier-pigeon"}})
+	if err := ot.setupPropagators(); err == nil {
+		t.Error("setupPropagators() with an unknown propagator succeeded")
+	}
+}
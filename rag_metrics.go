@@ -0,0 +1,249 @@
+package opentelemetry
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/firebase/genkit/go/ai"
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Limits for the document IDs attached to retriever spans.
+const (
+	retrieverDocumentIDsAttr = "genkit.retriever.document_ids"
+	maxDocumentIDs           = 50
+	maxDocumentIDLength      = 128
+)
+
+// embeddingTokenCountKey is the embedding metadata key read for token counts.
+// Genkit embed responses carry no usage, so tokens are only counted when the
+// embedder reports them there.
+const embeddingTokenCountKey = "tokenCount"
+
+// ragDocumentBuckets are the bucket boundaries for per-call document counts.
+var ragDocumentBuckets = []float64{0, 1, 2, 3, 5, 10, 20, 50, 100, 250, 500, 1000}
+
+// ragAction holds the request and latency instruments of one RAG action type.
+type ragAction struct {
+	requests otelmetric.Int64Counter
+	latency  otelmetric.Float64Histogram
+
+	// Metric attribute holding the action name, e.g. "retrieverName"
+	nameKey string
+}
+
+// ragMetrics is a span processor that derives retriever, embedder and indexer
+// metrics from finished spans.
+type ragMetrics struct {
+	actions map[string]*ragAction // by genkit:metadata:subtype
+
+	retrievedDocuments otelmetric.Int64Histogram
+	embedBatchSize     otelmetric.Int64Histogram
+	embedCharacters    otelmetric.Int64Counter
+	embedTokens        otelmetric.Int64Counter
+	indexedDocuments   otelmetric.Int64Counter
+
+	// Span attributes, such as promoted baggage keys, copied onto the
+	// retriever, embedder and indexer metrics
+	dimensionKeys []string
+
+	// Action inputs and outputs, read for document and batch counts
+	payloads *spanPayloads
+}
+
+// newRAGMetrics creates the RAG metric instruments on the given meter provider.
+// dimensionKeys are span attributes copied onto every data point.
+func newRAGMetrics(mp otelmetric.MeterProvider, dimensionKeys []string, payloads *spanPayloads) (*ragMetrics, error) {
+	meter := mp.Meter(genkitMeterName)
+	m := &ragMetrics{
+		actions:       make(map[string]*ragAction),
+		dimensionKeys: dimensionKeys,
+		payloads:      payloads,
+	}
+
+	for _, kind := range []string{"retriever", "embedder", "indexer"} {
+		requests, err := meter.Int64Counter("genkit/ai/"+kind+"/requests",
+			otelmetric.WithDescription("Counts calls to Genkit "+kind+"s."),
+			otelmetric.WithUnit("1"))
+		if err != nil {
+			return nil, err
+		}
+		latency, err := meter.Float64Histogram("genkit/ai/"+kind+"/latency",
+			otelmetric.WithDescription("Latencies when calling Genkit "+kind+"s."),
+			otelmetric.WithUnit("ms"),
+			otelmetric.WithExplicitBucketBoundaries(DefaultDurationBucketsMs...))
+		if err != nil {
+			return nil, err
+		}
+		m.actions[kind] = &ragAction{requests: requests, latency: latency, nameKey: kind + "Name"}
+	}
+
+	var err error
+	if m.retrievedDocuments, err = meter.Int64Histogram("genkit/ai/retriever/documents",
+		otelmetric.WithDescription("Number of documents returned per retriever call."),
+		otelmetric.WithUnit("{document}"),
+		otelmetric.WithExplicitBucketBoundaries(ragDocumentBuckets...)); err != nil {
+		return nil, err
+	}
+	if m.embedBatchSize, err = meter.Int64Histogram("genkit/ai/embedder/batch_size",
+		otelmetric.WithDescription("Number of documents embedded per embedder call."),
+		otelmetric.WithUnit("{document}"),
+		otelmetric.WithExplicitBucketBoundaries(ragDocumentBuckets...)); err != nil {
+		return nil, err
+	}
+	if m.embedCharacters, err = meter.Int64Counter("genkit/ai/embedder/input/characters",
+		otelmetric.WithDescription("Counts input characters to Genkit embedders."),
+		otelmetric.WithUnit("1")); err != nil {
+		return nil, err
+	}
+	if m.embedTokens, err = meter.Int64Counter("genkit/ai/embedder/input/tokens",
+		otelmetric.WithDescription("Counts input tokens to Genkit embedders that report them."),
+		otelmetric.WithUnit("1")); err != nil {
+		return nil, err
+	}
+	if m.indexedDocuments, err = meter.Int64Counter("genkit/ai/indexer/documents",
+		otelmetric.WithDescription("Counts documents passed to Genkit indexers."),
+		otelmetric.WithUnit("{document}")); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// OnStart implements trace.SpanProcessor.
+func (m *ragMetrics) OnStart(context.Context, trace.ReadWriteSpan) {}
+
+// OnEnd implements trace.SpanProcessor.
+func (m *ragMetrics) OnEnd(span trace.ReadOnlySpan) {
+	attrs := span.Attributes()
+	if stringAttribute(attrs, genkitTypeAttr) != "action" {
+		return
+	}
+	subtype := stringAttribute(attrs, genkitSubtypeAttr)
+	action, ok := m.actions[subtype]
+	if !ok {
+		return
+	}
+
+	ctx := spanContext(span)
+	path := stringAttribute(attrs, genkitPathAttr)
+	featureName := featureNameFromPath(path)
+	if featureName == "<unknown>" {
+		featureName = subtype
+	}
+
+	dims := []attribute.KeyValue{
+		attribute.String(action.nameKey, truncate(stringAttribute(attrs, genkitNameAttr), 1024)),
+		attribute.String("featureName", featureName),
+		attribute.String("source", "go"),
+		attribute.String("sourceVersion", genkitSourceVersion()),
+	}
+	dims = append(dims, promotedAttributes(attrs, m.dimensionKeys)...)
+	opt := otelmetric.WithAttributes(dims...)
+
+	if errorName := spanErrorName(span); errorName != "" {
+		action.requests.Add(ctx, 1, otelmetric.WithAttributes(append(dims,
+			attribute.String("status", "failure"), attribute.String("error", errorName))...))
+		action.latency.Record(ctx, spanLatencyMs(span), opt)
+		return
+	}
+	action.requests.Add(ctx, 1, otelmetric.WithAttributes(append(dims, attribute.String("status", "success"))...))
+	action.latency.Record(ctx, spanLatencyMs(span), opt)
+
+	switch subtype {
+	case "retriever":
+		if output, ok := decodePayload[ai.RetrieverResponse](m.payloads, span, genkitOutputAttr); ok {
+			m.retrievedDocuments.Record(ctx, int64(len(output.Documents)), opt)
+		}
+	case "embedder":
+		if input, ok := decodePayload[ai.EmbedRequest](m.payloads, span, genkitInputAttr); ok {
+			m.embedBatchSize.Record(ctx, int64(len(input.Input)), opt)
+			m.embedCharacters.Add(ctx, int64(documentCharacters(input.Input)), opt)
+		}
+		if output, ok := decodePayload[ai.EmbedResponse](m.payloads, span, genkitOutputAttr); ok {
+			if tokens, ok := embeddingTokens(output.Embeddings); ok {
+				m.embedTokens.Add(ctx, tokens, opt)
+			}
+		}
+	case "indexer":
+		if input, ok := decodePayload[indexRequest](m.payloads, span, genkitInputAttr); ok {
+			m.indexedDocuments.Add(ctx, int64(len(input.Documents)), opt)
+		}
+	}
+}
+
+// Shutdown implements trace.SpanProcessor.
+func (m *ragMetrics) Shutdown(context.Context) error { return nil }
+
+// ForceFlush implements trace.SpanProcessor.
+func (m *ragMetrics) ForceFlush(context.Context) error { return nil }
+
+// documentIDTransform returns a span transform that adds the IDs of the
+// retrieved documents, read from the given metadata key, to retriever spans.
+func documentIDTransform(key string, payloads *spanPayloads) spanTransform {
+	return func(span trace.ReadOnlySpan) trace.ReadOnlySpan {
+		if stringAttribute(span.Attributes(), genkitSubtypeAttr) != "retriever" {
+			return span
+		}
+		output, ok := decodePayload[ai.RetrieverResponse](payloads, span, genkitOutputAttr)
+		if !ok {
+			return span
+		}
+
+		var ids []string
+		for _, doc := range output.Documents {
+			if len(ids) == maxDocumentIDs {
+				break
+			}
+			if doc == nil {
+				continue
+			}
+			if id, ok := doc.Metadata[key]; ok {
+				ids = append(ids, truncate(fmt.Sprint(id), maxDocumentIDLength))
+			}
+		}
+		if len(ids) == 0 {
+			return span
+		}
+		return withAttributes(span, attribute.StringSlice(retrieverDocumentIDsAttr, ids))
+	}
+}
+
+// indexRequest is the input of an indexer action.
+type indexRequest struct {
+	Documents []*ai.Document `json:"documents"`
+}
+
+// documentCharacters returns the number of text characters in docs.
+func documentCharacters(docs []*ai.Document) int {
+	var n int
+	for _, doc := range docs {
+		if doc == nil {
+			continue
+		}
+		for _, part := range doc.Content {
+			if part != nil && part.IsText() {
+				n += utf8.RuneCountInString(part.Text)
+			}
+		}
+	}
+	return n
+}
+
+// embeddingTokens sums the token counts reported in embedding metadata.
+func embeddingTokens(embeddings []*ai.Embedding) (int64, bool) {
+	var total int64
+	var found bool
+	for _, e := range embeddings {
+		if e == nil {
+			continue
+		}
+		if count, ok := e.Metadata[embeddingTokenCountKey].(float64); ok {
+			total += int64(count)
+			found = true
+		}
+	}
+	return total, found
+}
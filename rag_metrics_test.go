@@ -0,0 +1,75 @@
+package opentelemetry_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+	opentelemetry "github.com/xavidop/genkit-opentelemetry-go"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestRAGMetrics(t *testing.T) {
+	tel, g := newTestGenkit(t, opentelemetry.Config{DocumentIDMetadataKey: "id"})
+	genkit.DefineRetriever(g, "test/retriever", nil, func(ctx context.Context, req *ai.RetrieverRequest) (*ai.RetrieverResponse, error) {
+		if req.Query.Content[0].Text == "fail" {
+			return nil, errors.New("index unavailable")
+		}
+		resp := &ai.RetrieverResponse{}
+		for _, id := range []string{"doc-1", "doc-2", "doc-3"} {
+			resp.Documents = append(resp.Documents, ai.DocumentFromText(id, map[string]any{"id": id}))
+		}
+		return resp, nil
+	})
+	genkit.DefineEmbedder(g, "test/embedder", nil, func(ctx context.Context, req *ai.EmbedRequest) (*ai.EmbedResponse, error) {
+		resp := &ai.EmbedResponse{}
+		for range req.Input {
+			resp.Embeddings = append(resp.Embeddings, &ai.Embedding{Embedding: []float32{1}, Metadata: map[string]any{"tokenCount": 4}})
+		}
+		return resp, nil
+	})
+	flow := genkit.DefineFlow(g, "ragFlow", func(ctx context.Context, input string) (string, error) {
+		if _, err := genkit.Embed(ctx, g, ai.WithEmbedderName("test/embedder"), ai.WithTextDocs("héllo", "ab")); err != nil {
+			return "", err
+		}
+		if _, err := genkit.Retrieve(ctx, g, ai.WithRetrieverName("test/retriever"), ai.WithTextDocs(input)); err != nil {
+			return "", err
+		}
+		return "", nil
+	})
+	runFlow(t, flow, "query")
+
+	retriever := []attribute.KeyValue{
+		attribute.String("retrieverName", "test/retriever"),
+		attribute.String("featureName", "ragFlow"),
+	}
+	tel.AssertMetric("genkit/ai/retriever/requests", 1, append(retriever, attribute.String("status", "success"))...)
+	tel.AssertMetric("genkit/ai/retriever/latency", 1, retriever...)
+	if documents := tel.AssertMetric("genkit/ai/retriever/documents", 1, retriever...); documents.Value != 3 {
+		t.Errorf("genkit/ai/retriever/documents sum = %v, want 3", documents.Value)
+	}
+	tel.AssertSpan("test/retriever", attribute.StringSlice("genkit.retriever.document_ids", []string{"doc-1", "doc-2", "doc-3"}))
+
+	embedder := attribute.String("embedderName", "test/embedder")
+	tel.AssertMetric("genkit/ai/embedder/requests", 1, embedder, attribute.String("status", "success"))
+	tel.AssertMetric("genkit/ai/embedder/latency", 1, embedder)
+	if batch := tel.AssertMetric("genkit/ai/embedder/batch_size", 1, embedder); batch.Value != 2 {
+		t.Errorf("genkit/ai/embedder/batch_size sum = %v, want 2", batch.Value)
+	}
+	tel.AssertMetric("genkit/ai/embedder/input/characters", 7, embedder)
+	tel.AssertMetric("genkit/ai/embedder/input/tokens", 8, embedder)
+
+	// Failed calls are counted with their error and record no documents
+	tel.Reset()
+	if _, err := flow.Run(context.Background(), "fail"); err == nil {
+		t.Fatal("flow.Run() succeeded, want the retriever error")
+	}
+	tel.AssertMetric("genkit/ai/retriever/requests", 1, append(retriever, attribute.String("status", "failure"))...)
+	for _, point := range tel.Metrics.Points() {
+		if point.Name == "genkit/ai/retriever/documents" {
+			t.Errorf("genkit/ai/retriever/documents recorded %d times for a failed call", point.Count)
+		}
+	}
+}
@@ -0,0 +1,400 @@
+package opentelemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+// configFilePollInterval is how often Config.ConfigFile is checked for changes.
+const configFilePollInterval = 5 * time.Second
+
+// ConfigPatch lists settings to change with Reconfigure. Nil fields are left
+// unchanged. It is also the format of Config.ConfigFile, e.g.
+//
+//	{"logLevel": "DEBUG", "sampleRatio": 0.1, "otlpEndpoint": "collector:4317"}
+type ConfigPatch struct {
+	// Minimum level of the plugin's log handler. With a custom LogHandler,
+	// records below it are dropped in addition to the handler's own filtering.
+	LogLevel *slog.Level `json:"logLevel,omitempty"`
+
+	// Fraction of new traces to sample, from 0 to 1. Spans with a parent
+	// follow their parent's decision.
+	SampleRatio *float64 `json:"sampleRatio,omitempty"`
+
+	// Sampler replacing the current one. Takes precedence over SampleRatio.
+	Sampler trace.Sampler `json:"-"`
+
+	// OTLP endpoint and headers of the default exporters. They cannot be
+	// changed for a custom TraceExporter or MetricExporter.
+	OTLPEndpoint *string           `json:"otlpEndpoint,omitempty"`
+	OTLPHeaders  map[string]string `json:"otlpHeaders,omitempty"`
+
+	// Span filters replacing Config.SpanFilters. An empty list removes them.
+	// Spans already started may be filtered with either.
+	SpanFilters []SpanFilter `json:"spanFilters,omitempty"`
+
+	// GenAI convention settings replacing Config.GenAIConventions, e.g. to
+	// leave out message content. Turns the translation on if it was off.
+	GenAIConventions *GenAIConventions `json:"genAIConventions,omitempty"`
+}
+
+// Reconfigure changes settings of the running plugin without recreating it.
+// All changes of the patch take effect together, or none of them if one is
+// invalid. Spans started concurrently use either the old or the new settings.
+// It fails once Shutdown has been called, as new exporters would not be shut
+// down.
+func (ot *OpenTelemetry) Reconfigure(ctx context.Context, patch ConfigPatch) error {
+	ot.reconfigureMu.Lock()
+	defer ot.reconfigureMu.Unlock()
+
+	if ot.shutDown {
+		return errors.New("plugin is shut down")
+	}
+	if !ot.ready.Load() {
+		return errors.New("plugin is not initialized")
+	}
+
+	// Validate and build everything before changing anything
+	var sampler trace.Sampler
+	switch {
+	case patch.Sampler != nil:
+		sampler = patch.Sampler
+	case patch.SampleRatio != nil:
+		ratio := *patch.SampleRatio
+		if ratio < 0 || ratio > 1 || math.IsNaN(ratio) {
+			return fmt.Errorf("sample ratio %v is not between 0 and 1", ratio)
+		}
+		sampler = trace.ParentBased(trace.TraceIDRatioBased(ratio))
+	}
+	if patch.SpanFilters != nil {
+		if err := validateSpanFilters(patch.SpanFilters); err != nil {
+			return err
+		}
+	}
+
+	config := ot.GetConfig()
+	patch.applyTo(&config)
+	var spanExporter trace.SpanExporter
+	var metricExporter metric.Exporter
+	if patch.OTLPEndpoint != nil || patch.OTLPHeaders != nil {
+		if ot.traceExporter == nil && ot.metricExporter == nil {
+			return errors.New("the OTLP endpoint can only be changed for the default exporters")
+		}
+		var err error
+		spanExporter, metricExporter, err = ot.withConfig(config).createDefaultExporters(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Apply
+	if patch.LogLevel != nil {
+		ot.logLevel.Set(*patch.LogLevel)
+	}
+	if sampler != nil {
+		ot.sampler.set(sampler)
+		config.Sampler = sampler
+	}
+	if patch.SpanFilters != nil {
+		_ = ot.spanFilters.setFilters(patch.SpanFilters)
+	}
+	if patch.GenAIConventions != nil {
+		conventions := *patch.GenAIConventions
+		ot.genAIConventions.Store(&conventions)
+	}
+	var old []func(context.Context) error
+	if spanExporter != nil {
+		old = append(old, ot.traceExporter.swap(spanExporter).Shutdown)
+	}
+	if metricExporter != nil {
+		old = append(old, ot.metricExporter.swap(metricExporter).Shutdown)
+	}
+
+	// Report the changes in GetConfig
+	ot.configMu.Lock()
+	ot.reconfigured = &config
+	ot.configMu.Unlock()
+
+	slog.Info("Reconfigured OpenTelemetry plugin", "patch", patch.String())
+
+	var errs []error
+	for _, shutdown := range old {
+		if err := shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to shut down replaced exporter: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// applyTo sets the fields of config that the patch changes, except the
+// sampler.
+func (p ConfigPatch) applyTo(config *Config) {
+	if p.LogLevel != nil {
+		config.LogLevel = *p.LogLevel
+	}
+	if p.OTLPEndpoint != nil {
+		config.OTLPEndpoint = *p.OTLPEndpoint
+	}
+	if p.OTLPHeaders != nil {
+		config.OTLPHeaders = p.OTLPHeaders
+	}
+	if p.SpanFilters != nil {
+		config.SpanFilters = slices.Clone(p.SpanFilters)
+	}
+	if p.GenAIConventions != nil {
+		conventions := *p.GenAIConventions
+		config.GenAIConventions = &conventions
+	}
+}
+
+// String lists the fields set in the patch, leaving out header values.
+func (p ConfigPatch) String() string {
+	var b bytes.Buffer
+	if p.LogLevel != nil {
+		fmt.Fprintf(&b, " logLevel=%s", p.LogLevel)
+	}
+	if p.Sampler != nil {
+		fmt.Fprintf(&b, " sampler=%s", p.Sampler.Description())
+	} else if p.SampleRatio != nil {
+		fmt.Fprintf(&b, " sampleRatio=%v", *p.SampleRatio)
+	}
+	if p.OTLPEndpoint != nil {
+		fmt.Fprintf(&b, " otlpEndpoint=%s", *p.OTLPEndpoint)
+	}
+	if p.OTLPHeaders != nil {
+		fmt.Fprintf(&b, " otlpHeaders=%d", len(p.OTLPHeaders))
+	}
+	if p.SpanFilters != nil {
+		fmt.Fprintf(&b, " spanFilters=%d", len(p.SpanFilters))
+	}
+	if c := p.GenAIConventions; c != nil {
+		fmt.Fprintf(&b, " genAIConventions=dropGenkitAttributes:%t,omitContent:%t", c.DropGenkitAttributes, c.OmitContent)
+	}
+	return string(bytes.TrimSpace(b.Bytes()))
+}
+
+// withConfig returns a copy of the plugin using config, for building
+// exporters from settings that are not applied yet.
+func (ot *OpenTelemetry) withConfig(config Config) *OpenTelemetry {
+	return &OpenTelemetry{config: config, presetType: ot.presetType, resource: ot.resource}
+}
+
+// createDefaultExporters creates the default exporters of the signals whose
+// exporter can be swapped.
+func (ot *OpenTelemetry) createDefaultExporters(ctx context.Context) (trace.SpanExporter, metric.Exporter, error) {
+	var spanExporter trace.SpanExporter
+	var metricExporter metric.Exporter
+	var err error
+	if ot.config.TraceExporter == nil {
+		if spanExporter, err = ot.createDefaultTraceExporter(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+	if ot.config.MetricExporter == nil && !ot.config.EnablePrometheusExporter && !ot.isPreset(PresetPrometheus) {
+		if metricExporter, err = ot.createDefaultMetricExporter(ctx); err != nil {
+			if spanExporter != nil {
+				_ = spanExporter.Shutdown(ctx)
+			}
+			return nil, nil, err
+		}
+	}
+	return spanExporter, metricExporter, nil
+}
+
+// watchConfigFile applies Config.ConfigFile now and whenever it changes,
+// until ctx is done.
+func (ot *OpenTelemetry) watchConfigFile(ctx context.Context, path string) {
+	var modTime time.Time
+	var size int64 = -1
+	check := func() {
+		info, err := os.Stat(path)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				slog.Warn("Failed to check config file", "path", path, "error", err)
+			}
+			return
+		}
+		if info.ModTime().Equal(modTime) && info.Size() == size {
+			return
+		}
+		modTime, size = info.ModTime(), info.Size()
+
+		if err := ot.applyConfigFile(ctx, path); err != nil {
+			slog.Error("Failed to apply config file", "path", path, "error", err)
+		}
+	}
+
+	check()
+	ticker := time.NewTicker(configFilePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			check()
+		}
+	}
+}
+
+// applyConfigFile reads a ConfigPatch from path and applies it.
+func (ot *OpenTelemetry) applyConfigFile(ctx context.Context, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var patch ConfigPatch
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patch); err != nil {
+		return fmt.Errorf("invalid config file: %w", err)
+	}
+	return ot.Reconfigure(ctx, patch)
+}
+
+// dynamicSampler delegates to a sampler that can be replaced while spans are
+// being started.
+type dynamicSampler struct {
+	sampler atomic.Pointer[trace.Sampler]
+}
+
+// newDynamicSampler creates a dynamic sampler starting with sampler.
+func newDynamicSampler(sampler trace.Sampler) *dynamicSampler {
+	s := &dynamicSampler{}
+	s.set(sampler)
+	return s
+}
+
+// set replaces the sampler.
+func (s *dynamicSampler) set(sampler trace.Sampler) {
+	s.sampler.Store(&sampler)
+}
+
+// ShouldSample implements trace.Sampler.
+func (s *dynamicSampler) ShouldSample(parameters trace.SamplingParameters) trace.SamplingResult {
+	return (*s.sampler.Load()).ShouldSample(parameters)
+}
+
+// Description implements trace.Sampler.
+func (s *dynamicSampler) Description() string {
+	return (*s.sampler.Load()).Description()
+}
+
+// levelHandler drops records below a level that can be changed at runtime,
+// before the wrapped handler sees them.
+type levelHandler struct {
+	slog.Handler
+	level slog.Leveler
+}
+
+// Enabled implements slog.Handler.
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() && h.Handler.Enabled(ctx, level)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithAttrs(attrs), level: h.level}
+}
+
+// WithGroup implements slog.Handler.
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithGroup(name), level: h.level}
+}
+
+// swappableSpanExporter is a span exporter that can be replaced between
+// exports.
+type swappableSpanExporter struct {
+	mu       sync.RWMutex
+	exporter trace.SpanExporter
+}
+
+// swap replaces the exporter once in-flight exports finish, and returns the
+// previous one.
+func (e *swappableSpanExporter) swap(exporter trace.SpanExporter) trace.SpanExporter {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	old := e.exporter
+	e.exporter = exporter
+	return old
+}
+
+// ExportSpans implements trace.SpanExporter.
+func (e *swappableSpanExporter) ExportSpans(ctx context.Context, spans []trace.ReadOnlySpan) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.exporter.ExportSpans(ctx, spans)
+}
+
+// Shutdown implements trace.SpanExporter.
+func (e *swappableSpanExporter) Shutdown(ctx context.Context) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.exporter.Shutdown(ctx)
+}
+
+// swappableMetricExporter is a metric exporter that can be replaced between
+// exports. Replacements must use the same temporality and aggregation.
+type swappableMetricExporter struct {
+	mu       sync.RWMutex
+	exporter metric.Exporter
+}
+
+// swap replaces the exporter once in-flight exports finish, and returns the
+// previous one.
+func (e *swappableMetricExporter) swap(exporter metric.Exporter) metric.Exporter {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	old := e.exporter
+	e.exporter = exporter
+	return old
+}
+
+// Temporality implements metric.Exporter.
+func (e *swappableMetricExporter) Temporality(kind metric.InstrumentKind) metricdata.Temporality {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.exporter.Temporality(kind)
+}
+
+// Aggregation implements metric.Exporter.
+func (e *swappableMetricExporter) Aggregation(kind metric.InstrumentKind) metric.Aggregation {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.exporter.Aggregation(kind)
+}
+
+// Export implements metric.Exporter.
+func (e *swappableMetricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.exporter.Export(ctx, rm)
+}
+
+// ForceFlush implements metric.Exporter.
+func (e *swappableMetricExporter) ForceFlush(ctx context.Context) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.exporter.ForceFlush(ctx)
+}
+
+// Shutdown implements metric.Exporter.
+func (e *swappableMetricExporter) Shutdown(ctx context.Context) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.exporter.Shutdown(ctx)
+}
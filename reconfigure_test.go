@@ -0,0 +1,161 @@
+package opentelemetry_test
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	opentelemetry "github.com/xavidop/genkit-opentelemetry-go"
+	"github.com/xavidop/genkit-opentelemetry-go/testutil"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// loggedInfo reports whether an info record with msg reached the plugin's
+// log handler.
+func loggedInfo(tel *testutil.Telemetry, msg string) bool {
+	slog.Info(msg)
+	for _, record := range tel.Logs.Records() {
+		if record.Message == msg {
+			return true
+		}
+	}
+	return false
+}
+
+func TestReconfigureBeforeInit(t *testing.T) {
+	tel := testutil.NewForTest(t)
+	ratio := 0.5
+	if err := tel.Plugin.Reconfigure(context.Background(), opentelemetry.ConfigPatch{SampleRatio: &ratio}); err == nil {
+		t.Error("Reconfigure() before genkit.Init succeeded")
+	}
+}
+
+func TestReconfigureAfterShutdown(t *testing.T) {
+	tel, _ := newTestGenkit(t)
+	ctx := context.Background()
+	if err := tel.Plugin.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	before := tel.Plugin.GetConfig()
+	endpoint := "collector:4317"
+	err := tel.Plugin.Reconfigure(ctx, opentelemetry.ConfigPatch{
+		OTLPEndpoint: &endpoint,
+		SpanFilters:  []opentelemetry.SpanFilter{{Type: "util"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "shut down") {
+		t.Fatalf("Reconfigure() after Shutdown error = %v, want the plugin to be shut down", err)
+	}
+	if after := tel.Plugin.GetConfig(); after.OTLPEndpoint != before.OTLPEndpoint || len(after.SpanFilters) != 0 {
+		t.Errorf("GetConfig() = endpoint %q, filters %v after a rejected Reconfigure", after.OTLPEndpoint, after.SpanFilters)
+	}
+}
+
+func TestReconfigureAppliesPatch(t *testing.T) {
+	tel, g := newTestGenkit(t)
+	flow := defineGenerateFlow(g, "reconfiguredFlow")
+	ctx := context.Background()
+
+	level := slog.LevelWarn
+	err := tel.Plugin.Reconfigure(ctx, opentelemetry.ConfigPatch{
+		LogLevel:         &level,
+		SpanFilters:      []opentelemetry.SpanFilter{{Type: "util"}},
+		GenAIConventions: &opentelemetry.GenAIConventions{OmitContent: true},
+	})
+	if err != nil {
+		t.Fatalf("Reconfigure() error = %v", err)
+	}
+	if loggedInfo(tel, "after reconfigure") {
+		t.Error("info record was logged after raising the log level")
+	}
+	config := tel.Plugin.GetConfig()
+	if len(config.SpanFilters) != 1 || config.GenAIConventions == nil || !config.GenAIConventions.OmitContent {
+		t.Errorf("GetConfig() = filters %v, conventions %v, want the patched values", config.SpanFilters, config.GenAIConventions)
+	}
+
+	runFlow(t, flow, "hi")
+	spans := tel.FindFlowTrace("reconfiguredFlow")
+	if hasSpan(spans, "generate") {
+		t.Error("generate span was exported after adding a util filter")
+	}
+	model := tel.AssertSpan(testModel, attribute.String("gen_ai.operation.name", "chat"))
+	if len(model.Events) > 0 {
+		t.Error("model span has content events with OmitContent")
+	}
+
+	// An empty list removes the filters
+	if err := tel.Plugin.Reconfigure(ctx, opentelemetry.ConfigPatch{SpanFilters: []opentelemetry.SpanFilter{}}); err != nil {
+		t.Fatalf("Reconfigure() error = %v", err)
+	}
+	tel.Reset()
+	runFlow(t, flow, "hi")
+	if spans := tel.FindFlowTrace("reconfiguredFlow"); !hasSpan(spans, "generate") {
+		t.Error("generate span was dropped after removing the filters")
+	}
+
+	ratio := 0.0
+	if err := tel.Plugin.Reconfigure(ctx, opentelemetry.ConfigPatch{SampleRatio: &ratio}); err != nil {
+		t.Fatalf("Reconfigure() error = %v", err)
+	}
+	tel.Reset()
+	runFlow(t, flow, "hi")
+	tel.Flush()
+	if spans := tel.Spans.GetSpans(); len(spans) > 0 {
+		t.Errorf("%d spans exported with a sample ratio of 0", len(spans))
+	}
+}
+
+func TestReconfigureIsAtomic(t *testing.T) {
+	invalidRatio := 2.0
+	endpoint := "collector:4317"
+	tests := []struct {
+		name  string
+		patch func(opentelemetry.ConfigPatch) opentelemetry.ConfigPatch
+	}{
+		{"invalid sample ratio", func(p opentelemetry.ConfigPatch) opentelemetry.ConfigPatch {
+			p.SampleRatio = &invalidRatio
+			return p
+		}},
+		{"invalid span filter", func(p opentelemetry.ConfigPatch) opentelemetry.ConfigPatch {
+			p.SpanFilters = append(p.SpanFilters, opentelemetry.SpanFilter{})
+			return p
+		}},
+		{"endpoint of a custom exporter", func(p opentelemetry.ConfigPatch) opentelemetry.ConfigPatch {
+			p.OTLPEndpoint = &endpoint
+			return p
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tel, g := newTestGenkit(t)
+			flow := defineGenerateFlow(g, "atomicFlow")
+			before := tel.Plugin.GetConfig()
+
+			level := slog.LevelWarn
+			valid := opentelemetry.ConfigPatch{
+				LogLevel:         &level,
+				SpanFilters:      []opentelemetry.SpanFilter{{Type: "util"}},
+				GenAIConventions: &opentelemetry.GenAIConventions{},
+			}
+			if err := tel.Plugin.Reconfigure(context.Background(), tt.patch(valid)); err == nil {
+				t.Fatal("Reconfigure() succeeded, want an error")
+			}
+
+			after := tel.Plugin.GetConfig()
+			if after.LogLevel != before.LogLevel || len(after.SpanFilters) != 0 || after.GenAIConventions != nil || after.OTLPEndpoint != before.OTLPEndpoint {
+				t.Errorf("GetConfig() changed by a failed patch: %+v", after)
+			}
+			if !loggedInfo(tel, "after failed reconfigure") {
+				t.Error("log level changed by a failed patch")
+			}
+			runFlow(t, flow, "hi")
+			if spans := tel.FindFlowTrace("atomicFlow"); !hasSpan(spans, "generate") {
+				t.Error("span filters changed by a failed patch")
+			}
+			if model := tel.AssertSpan(testModel); hasAttribute(model, "gen_ai.operation.name") {
+				t.Error("GenAI conventions changed by a failed patch")
+			}
+		})
+	}
+}
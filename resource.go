@@ -0,0 +1,59 @@
+package opentelemetry
+
+import (
+	"context"
+	"os"
+
+	"go.opentelemetry.io/contrib/detectors/gcp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// gcpProjectIDAttr is the resource attribute Google Cloud's OTLP endpoint uses
+// to route telemetry to a project.
+const gcpProjectIDAttr = "gcp.project_id"
+
+// createResource builds the resource shared by the tracer and meter providers.
+// User supplied ResourceAttributes always take precedence over detected values.
+func (ot *OpenTelemetry) createResource(ctx context.Context) (*resource.Resource, error) {
+	opts := []resource.Option{
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	}
+
+	// Detect Cloud Run, GKE, GCE and Cloud Functions attributes for Google Cloud
+	if ot.isPreset(PresetGoogleCloud) {
+		opts = append(opts, resource.WithDetectors(gcp.NewDetector()))
+	}
+
+	attrs := []attribute.KeyValue{
+		semconv.ServiceName(ot.config.ServiceName),
+	}
+	if ot.config.ServiceVersion != "" {
+		attrs = append(attrs, semconv.ServiceVersion(ot.config.ServiceVersion))
+	}
+	if ot.config.GoogleCloudProjectID != "" {
+		attrs = append(attrs, attribute.String(gcpProjectIDAttr, ot.config.GoogleCloudProjectID))
+	}
+	for k, v := range ot.config.ResourceAttributes {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+	opts = append(opts, resource.WithAttributes(attrs...))
+
+	res, err := resource.New(ctx, opts...)
+	if err != nil && res == nil {
+		return nil, err
+	}
+	// Partial detection errors still yield a usable resource
+	return res, nil
+}
+
+// googleCloudProjectID returns the project ID from the environment, following
+// the same lookup order as the Genkit googlecloud plugin.
+func googleCloudProjectID() string {
+	if projectID := os.Getenv("GOOGLE_CLOUD_PROJECT"); projectID != "" {
+		return projectID
+	}
+	return os.Getenv("GCLOUD_PROJECT")
+}
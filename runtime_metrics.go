@@ -0,0 +1,179 @@
+package opentelemetry
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"runtime/metrics"
+	"strconv"
+	"sync"
+
+	"github.com/shirou/gopsutil/v4/process"
+	"go.opentelemetry.io/contrib/instrumentation/host"
+	"go.opentelemetry.io/contrib/instrumentation/runtime"
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+)
+
+// runtime/metrics samples not covered by the contrib runtime instrumentation.
+const (
+	gcCyclesMetric       = "/gc/cycles/total:gc-cycles"
+	schedLatenciesMetric = "/sched/latencies:seconds"
+)
+
+// schedLatencyQuantiles are reported for the go.schedule.latency gauge.
+var schedLatencyQuantiles = []float64{0.5, 0.9, 0.99}
+
+// setupRuntimeMetrics registers Go runtime, process and host metrics on the
+// plugin's meter provider.
+func (ot *OpenTelemetry) setupRuntimeMetrics() error {
+	if ot.meterProvider == nil {
+		return nil
+	}
+
+	// Heap, allocations, goroutines, GC goal, GOMAXPROCS and GOGC
+	if err := runtime.Start(
+		runtime.WithMeterProvider(ot.meterProvider),
+		runtime.WithMinimumReadMemStatsInterval(ot.config.MetricInterval),
+	); err != nil {
+		return fmt.Errorf("failed to start runtime metrics: %w", err)
+	}
+
+	// Process and host CPU, host memory and network
+	if err := host.Start(host.WithMeterProvider(ot.meterProvider)); err != nil {
+		return fmt.Errorf("failed to start host metrics: %w", err)
+	}
+
+	return registerProcessMetrics(ot.meterProvider.Meter(instrumentationName))
+}
+
+// processMetrics reports GC cycles, scheduler latency, RSS and open file
+// descriptors, which the contrib instrumentation does not cover.
+type processMetrics struct {
+	proc    *process.Process
+	samples []metrics.Sample
+
+	mu sync.Mutex
+	// Scheduler latency bucket counts at the previous collection, so each
+	// collection reports the quantiles of the latest interval only
+	prevSched []uint64
+}
+
+// registerProcessMetrics creates the process metric instruments on meter.
+func registerProcessMetrics(meter otelmetric.Meter) error {
+	pid := os.Getpid()
+	if pid > math.MaxInt32 {
+		return fmt.Errorf("invalid process ID: %d", pid)
+	}
+	proc, err := process.NewProcess(int32(pid))
+	if err != nil {
+		return fmt.Errorf("could not find this process: %w", err)
+	}
+
+	p := &processMetrics{
+		proc: proc,
+		samples: []metrics.Sample{
+			{Name: gcCyclesMetric},
+			{Name: schedLatenciesMetric},
+		},
+	}
+
+	gcCount, err := meter.Int64ObservableCounter("go.gc.count",
+		otelmetric.WithDescription("Number of completed GC cycles."),
+		otelmetric.WithUnit("{gc_cycle}"))
+	if err != nil {
+		return err
+	}
+	schedLatency, err := meter.Float64ObservableGauge("go.schedule.latency",
+		otelmetric.WithDescription("Time goroutines spent runnable before running, by quantile over the last collection interval."),
+		otelmetric.WithUnit("s"))
+	if err != nil {
+		return err
+	}
+	memoryUsage, err := meter.Int64ObservableUpDownCounter("process.memory.usage",
+		otelmetric.WithDescription("The amount of physical memory in use."),
+		otelmetric.WithUnit("By"))
+	if err != nil {
+		return err
+	}
+	openFDs, err := meter.Int64ObservableUpDownCounter("process.open_file_descriptor.count",
+		otelmetric.WithDescription("Number of file descriptors in use by the process."),
+		otelmetric.WithUnit("{file_descriptor}"))
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.RegisterCallback(func(ctx context.Context, o otelmetric.Observer) error {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		metrics.Read(p.samples)
+		if v := p.samples[0].Value; v.Kind() == metrics.KindUint64 {
+			o.ObserveInt64(gcCount, clampInt64(v.Uint64()))
+		}
+		if v := p.samples[1].Value; v.Kind() == metrics.KindFloat64Histogram {
+			for q, latency := range p.schedQuantiles(v.Float64Histogram()) {
+				o.ObserveFloat64(schedLatency, latency,
+					otelmetric.WithAttributes(attribute.String("quantile", strconv.FormatFloat(q, 'f', -1, 64))))
+			}
+		}
+
+		// RSS and file descriptors are not available on every platform
+		if mem, err := p.proc.MemoryInfoWithContext(ctx); err == nil {
+			o.ObserveInt64(memoryUsage, clampInt64(mem.RSS))
+		}
+		if fds, err := p.proc.NumFDsWithContext(ctx); err == nil {
+			o.ObserveInt64(openFDs, int64(fds))
+		}
+		return nil
+	}, gcCount, schedLatency, memoryUsage, openFDs)
+	return err
+}
+
+// schedQuantiles returns the scheduler latency quantiles of the goroutines
+// scheduled since the previous call. It returns nil if none were.
+func (p *processMetrics) schedQuantiles(h *metrics.Float64Histogram) map[float64]float64 {
+	delta := make([]uint64, len(h.Counts))
+	var total uint64
+	for i, count := range h.Counts {
+		if i < len(p.prevSched) {
+			delta[i] = count - p.prevSched[i]
+		} else {
+			delta[i] = count
+		}
+		total += delta[i]
+	}
+	p.prevSched = append(p.prevSched[:0], h.Counts...)
+	if total == 0 {
+		return nil
+	}
+
+	quantiles := make(map[float64]float64, len(schedLatencyQuantiles))
+	for _, q := range schedLatencyQuantiles {
+		target := uint64(math.Ceil(q * float64(total)))
+		var seen uint64
+		for i, count := range delta {
+			seen += count
+			if seen >= target {
+				// Report the upper bound of the bucket, falling back to its
+				// lower bound for the open-ended last bucket
+				upper := h.Buckets[i+1]
+				if math.IsInf(upper, 1) {
+					upper = h.Buckets[i]
+				}
+				quantiles[q] = upper
+				break
+			}
+		}
+	}
+	return quantiles
+}
+
+// clampInt64 converts v to an int64, saturating at math.MaxInt64.
+func clampInt64(v uint64) int64 {
+	if v > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(v)
+}
@@ -0,0 +1,40 @@
+package opentelemetry
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestRuntimeMetricsRegistered(t *testing.T) {
+	provider, reader := newManualMeterProvider(t, Config{})
+	ot := New(Config{EnableRuntimeMetrics: true})
+	ot.meterProvider = provider
+	if err := ot.setupRuntimeMetrics(); err != nil {
+		t.Fatalf("setupRuntimeMetrics() error = %v", err)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	collected := map[string]bool{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			collected[m.Name] = true
+		}
+	}
+	for _, name := range []string{
+		// Go runtime
+		"go.memory.used", "go.goroutine.count", "go.processor.limit",
+		// Process and host
+		"process.cpu.time", "system.memory.usage",
+		// Registered by the plugin
+		"go.gc.count", "go.schedule.latency", "process.memory.usage", "process.open_file_descriptor.count",
+	} {
+		if !collected[name] {
+			t.Errorf("metric %q was not collected", name)
+		}
+	}
+}
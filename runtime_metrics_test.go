@@ -0,0 +1,29 @@
+package opentelemetry_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/firebase/genkit/go/genkit"
+	opentelemetry "github.com/xavidop/genkit-opentelemetry-go"
+	"github.com/xavidop/genkit-opentelemetry-go/testutil"
+)
+
+func TestRuntimeMetricsAreOptIn(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		tel := testutil.NewForTest(t, opentelemetry.Config{EnableRuntimeMetrics: enabled})
+		genkit.Init(context.Background(), genkit.WithPlugins(tel.Plugin))
+		tel.Flush()
+
+		exported := slices.ContainsFunc(tel.Metrics.Points(), func(point testutil.MetricPoint) bool {
+			return point.Name == "go.goroutine.count"
+		})
+		if exported != enabled {
+			t.Errorf("EnableRuntimeMetrics = %v: go.goroutine.count exported = %v", enabled, exported)
+		}
+		if err := tel.Plugin.Shutdown(context.Background()); err != nil {
+			t.Fatalf("Shutdown() error = %v", err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package opentelemetry
+
+import (
+	"context"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// Attribute keys Genkit sets on its spans.
+const (
+	genkitTypeAttr            = "genkit:type"
+	genkitNameAttr            = "genkit:name"
+	genkitPathAttr            = "genkit:path"
+	genkitStateAttr           = "genkit:state"
+	genkitInputAttr           = "genkit:input"
+	genkitOutputAttr          = "genkit:output"
+	genkitSubtypeAttr         = "genkit:metadata:subtype"
+	genkitIsRootAttr          = "genkit:isRoot"
+	genkitIsFailureSourceAttr = "genkit:isFailureSource"
+)
+
+// genkitModulePath is the module path of the Genkit Go SDK.
+const genkitModulePath = "github.com/firebase/genkit/go"
+
+// stringAttribute returns the string value of key, or "" if it is not set.
+func stringAttribute(attrs []attribute.KeyValue, key string) string {
+	for _, attr := range attrs {
+		if string(attr.Key) == key {
+			return attr.Value.Emit()
+		}
+	}
+	return ""
+}
+
+// boolAttribute returns the bool value of key, or false if it is not set.
+func boolAttribute(attrs []attribute.KeyValue, key string) bool {
+	for _, attr := range attrs {
+		if string(attr.Key) == key && attr.Value.Type() == attribute.BOOL {
+			return attr.Value.AsBool()
+		}
+	}
+	return false
+}
+
+// spanLatencyMs returns the duration of a finished span in milliseconds.
+func spanLatencyMs(span trace.ReadOnlySpan) float64 {
+	endTime := span.EndTime()
+	if endTime.IsZero() {
+		endTime = time.Now()
+	}
+	return float64(endTime.Sub(span.StartTime()).Nanoseconds()) / 1e6
+}
+
+// featureNameFromPath extracts the outermost feature name from a Genkit path,
+// e.g. "/{myFlow,t:flow}/{googleai/gemini,t:action,s:model}" returns "myFlow".
+func featureNameFromPath(path string) string {
+	first, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if !strings.HasPrefix(first, "{") {
+		return "<unknown>"
+	}
+	name, _, found := strings.Cut(first[1:], ",")
+	if !found || name == "" {
+		return "<unknown>"
+	}
+	return name
+}
+
+var (
+	genkitVersionOnce sync.Once
+	genkitVersion     string
+)
+
+// genkitSourceVersion returns the version of the Genkit Go SDK linked into
+// the binary, as reported by the build info.
+func genkitSourceVersion() string {
+	genkitVersionOnce.Do(func() {
+		genkitVersion = "unknown"
+		info, ok := debug.ReadBuildInfo()
+		if !ok {
+			return
+		}
+		for _, dep := range info.Deps {
+			if dep.Path == genkitModulePath {
+				genkitVersion = strings.TrimPrefix(dep.Version, "v")
+				return
+			}
+		}
+	})
+	return genkitVersion
+}
+
+// spanContext returns a context carrying the span context of a finished span,
+// so measurements derived from the span can be sampled as its exemplars.
+func spanContext(span trace.ReadOnlySpan) context.Context {
+	return oteltrace.ContextWithSpanContext(context.Background(), span.SpanContext())
+}
@@ -0,0 +1,72 @@
+package opentelemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+// spanTransform adjusts a finished span before it is exported. Returning nil
+// drops the span.
+type spanTransform func(span trace.ReadOnlySpan) trace.ReadOnlySpan
+
+// transformingSpanExporter applies span transforms before delegating to
+// another exporter. Finished spans are read-only, so this is where attributes
+// derived at the end of a span are added.
+type transformingSpanExporter struct {
+	exporter   trace.SpanExporter
+	transforms []spanTransform
+}
+
+// newTransformingSpanExporter wraps exporter with the given transforms.
+func newTransformingSpanExporter(exporter trace.SpanExporter, transforms ...spanTransform) *transformingSpanExporter {
+	return &transformingSpanExporter{
+		exporter:   exporter,
+		transforms: transforms,
+	}
+}
+
+// ExportSpans implements trace.SpanExporter.
+func (e *transformingSpanExporter) ExportSpans(ctx context.Context, spans []trace.ReadOnlySpan) error {
+	adjusted := make([]trace.ReadOnlySpan, 0, len(spans))
+	for _, span := range spans {
+		for _, transform := range e.transforms {
+			if span = transform(span); span == nil {
+				break
+			}
+		}
+		if span != nil {
+			adjusted = append(adjusted, span)
+		}
+	}
+	if len(adjusted) == 0 {
+		return nil
+	}
+	return e.exporter.ExportSpans(ctx, adjusted)
+}
+
+// Shutdown implements trace.SpanExporter.
+func (e *transformingSpanExporter) Shutdown(ctx context.Context) error {
+	return e.exporter.Shutdown(ctx)
+}
+
+// spanWithAttributes overrides the attributes of a finished span.
+type spanWithAttributes struct {
+	trace.ReadOnlySpan
+	attrs []attribute.KeyValue
+}
+
+// Attributes implements trace.ReadOnlySpan.
+func (s *spanWithAttributes) Attributes() []attribute.KeyValue {
+	return s.attrs
+}
+
+// withAttributes returns span with the given attributes appended.
+func withAttributes(span trace.ReadOnlySpan, attrs ...attribute.KeyValue) trace.ReadOnlySpan {
+	existing := span.Attributes()
+	merged := make([]attribute.KeyValue, 0, len(existing)+len(attrs))
+	merged = append(merged, existing...)
+	merged = append(merged, attrs...)
+	return &spanWithAttributes{ReadOnlySpan: span, attrs: merged}
+}
@@ -0,0 +1,441 @@
+package opentelemetry
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// SpanFilter matches finished spans to leave out of the export, or to export
+// without some of their attributes. Every field that is set must match, where
+// "*" in a pattern matches any sequence of characters. Spans are only dropped
+// if the filter also matches when the span starts, which Genkit spans do, so
+// a filter dropping spans must match on Name, Type or GenkitName.
+type SpanFilter struct {
+	// Pattern for the span name.
+	Name string `json:"name,omitempty"`
+
+	// Pattern for genkit:type, e.g. "util".
+	Type string `json:"type,omitempty"`
+
+	// Pattern for genkit:name.
+	GenkitName string `json:"genkitName,omitempty"`
+
+	// Patterns for attribute values by key, e.g.
+	// {"genkit:metadata:subtype": "util"}. Spans without the attribute do
+	// not match.
+	Attributes map[string]string `json:"attributes,omitempty"`
+
+	// Patterns for the keys of attributes to remove from matching spans,
+	// e.g. "genkit:input" or "genkit:metadata:*". If empty, matching spans
+	// are dropped and their children attached to the nearest exported
+	// ancestor.
+	DropAttributes []string `json:"dropAttributes,omitempty"`
+}
+
+// validate checks that the filter cannot match every span by accident, and
+// that spans it drops can be told apart when they start.
+func (f *SpanFilter) validate() error {
+	if f.Name == "" && f.Type == "" && f.GenkitName == "" && len(f.Attributes) == 0 {
+		return fmt.Errorf("span filter must match on a name, type, genkit name or attribute")
+	}
+	if len(f.DropAttributes) == 0 && f.Name == "" && f.Type == "" && f.GenkitName == "" {
+		return fmt.Errorf("span filter dropping spans must match on a name, type or genkit name")
+	}
+	return nil
+}
+
+// validateSpanFilters checks every filter of a list.
+func validateSpanFilters(filters []SpanFilter) error {
+	for i := range filters {
+		if err := filters[i].validate(); err != nil {
+			return fmt.Errorf("span filter %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// matches reports whether span matches every criterion of the filter.
+func (f *SpanFilter) matches(span trace.ReadOnlySpan) bool {
+	if f.Name != "" && !matchPattern(f.Name, span.Name()) {
+		return false
+	}
+	attrs := span.Attributes()
+	if f.Type != "" && !matchAttribute(attrs, genkitTypeAttr, f.Type) {
+		return false
+	}
+	if f.GenkitName != "" && !matchAttribute(attrs, genkitNameAttr, f.GenkitName) {
+		return false
+	}
+	for key, pattern := range f.Attributes {
+		if !matchAttribute(attrs, key, pattern) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchAttribute reports whether attrs has key with a value matching pattern.
+// Genkit sets some attributes both when a span starts and when it ends, so
+// the last value wins.
+func matchAttribute(attrs []attribute.KeyValue, key, pattern string) bool {
+	for i := len(attrs) - 1; i >= 0; i-- {
+		if string(attrs[i].Key) == key {
+			return matchPattern(pattern, attrs[i].Value.Emit())
+		}
+	}
+	return false
+}
+
+// filteredSpan tracks a span the span filter may have to hold back: one that
+// can still be dropped, a span waiting for such an ancestor to end, or a span
+// of a flow with a debug override.
+type filteredSpan struct {
+	id       oteltrace.SpanID
+	parent   *filteredSpan // Ancestor the span waits for, nil if none
+	children []*filteredSpan
+	span     trace.ReadOnlySpan // Set when the span ends
+
+	// Parent the span started with
+	startParent oteltrace.SpanContext
+
+	droppable bool // Matched a dropping filter when it started
+	dropped   bool // Matched a dropping filter when it ended
+	debug     bool // Part of a flow with a debug override
+	held      bool // Ended and holding a slot in the export queue
+
+	// Parent to export the span with, if an ancestor was dropped
+	reparent *oteltrace.SpanContext
+}
+
+// pending reports whether the parent of the span's children is undecided,
+// because the span may still be dropped or was dropped and waits itself.
+func (n *filteredSpan) pending() bool {
+	return n.droppable && (n.span == nil || (n.dropped && n.parent != nil))
+}
+
+// exportParent returns the parent the span is exported with.
+func (n *filteredSpan) exportParent() oteltrace.SpanContext {
+	if n.reparent != nil {
+		return *n.reparent
+	}
+	return n.startParent
+}
+
+// spanFilterProcessor applies span filters before handing spans to the
+// exporting processor. A dropped span may end after its children, so the
+// children of spans that can still be dropped are held until their fate is
+// known, which lets them be attached to the nearest exported ancestor. Only
+// Name and Type are known when a span starts, so other spans pass straight
+// through. Held spans take up room in the export queue; when it is full they
+// are released and the queue counts them as dropped. Traces of flows with a
+// debug override are not filtered. The filters can be replaced while spans
+// are being ended; spans already started may be filtered with either.
+type spanFilterProcessor struct {
+	trace.SpanProcessor
+	filters atomic.Pointer[[]SpanFilter]
+	stats   *pipelineStats
+
+	mu    sync.Mutex
+	spans map[oteltrace.SpanID]*filteredSpan
+}
+
+// newSpanFilterProcessor filters the spans ended on next, which counts its
+// queued spans in stats.
+func newSpanFilterProcessor(next trace.SpanProcessor, filters []SpanFilter, stats *pipelineStats) (*spanFilterProcessor, error) {
+	p := &spanFilterProcessor{
+		SpanProcessor: next,
+		stats:         stats,
+		spans:         map[oteltrace.SpanID]*filteredSpan{},
+	}
+	if err := p.setFilters(filters); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// setFilters replaces the filters, or removes them if filters is empty.
+func (p *spanFilterProcessor) setFilters(filters []SpanFilter) error {
+	if err := validateSpanFilters(filters); err != nil {
+		return err
+	}
+	filters = slices.Clone(filters)
+	p.filters.Store(&filters)
+	return nil
+}
+
+// droppable reports whether a span starting with name and attrs may match a
+// filter dropping it once it ends. Genkit sets genkit:name when a span ends,
+// to the span's name, so GenkitName is matched against the name until then.
+// Attributes not set yet may still match.
+func (p *spanFilterProcessor) droppable(name string, attrs []attribute.KeyValue) bool {
+	filters := *p.filters.Load()
+	for i := range filters {
+		filter := &filters[i]
+		if len(filter.DropAttributes) > 0 {
+			continue
+		}
+		if filter.Name != "" && !matchPattern(filter.Name, name) {
+			continue
+		}
+		if filter.Type != "" && !matchAttribute(attrs, genkitTypeAttr, filter.Type) {
+			continue
+		}
+		if filter.GenkitName != "" && !matchPattern(filter.GenkitName, name) && !matchAttribute(attrs, genkitNameAttr, filter.GenkitName) {
+			continue
+		}
+		if !mayMatchAttributes(attrs, filter.Attributes) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// mayMatchAttributes reports whether attrs match the patterns by key, or
+// may once the missing keys are set.
+func mayMatchAttributes(attrs []attribute.KeyValue, patterns map[string]string) bool {
+	for key, pattern := range patterns {
+		if slices.ContainsFunc(attrs, func(kv attribute.KeyValue) bool { return string(kv.Key) == key }) &&
+			!matchAttribute(attrs, key, pattern) {
+			return false
+		}
+	}
+	return true
+}
+
+// apply returns span without the attributes the filters drop, and whether a
+// filter drops the whole span. Only droppable spans are dropped.
+func (p *spanFilterProcessor) apply(span trace.ReadOnlySpan, droppable bool) (trace.ReadOnlySpan, bool) {
+	filters := *p.filters.Load()
+	var dropAttributes []string
+	for i := range filters {
+		filter := &filters[i]
+		if !filter.matches(span) {
+			continue
+		}
+		if len(filter.DropAttributes) == 0 {
+			if droppable {
+				return span, true
+			}
+			continue
+		}
+		dropAttributes = append(dropAttributes, filter.DropAttributes...)
+	}
+	if len(dropAttributes) > 0 {
+		span = withoutAttributes(span, dropAttributes)
+	}
+	return span, false
+}
+
+// OnStart implements trace.SpanProcessor.
+func (p *spanFilterProcessor) OnStart(ctx context.Context, span trace.ReadWriteSpan) {
+	p.SpanProcessor.OnStart(ctx, span)
+	if !span.SpanContext().IsSampled() {
+		return
+	}
+
+	attrs := span.Attributes()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	parent := p.spans[span.Parent().SpanID()]
+	debug := boolAttribute(attrs, debugAttr) || (parent != nil && parent.debug)
+	droppable := !debug && p.droppable(span.Name(), attrs)
+	waits := parent != nil && parent.pending()
+	if !debug && !droppable && !waits {
+		return
+	}
+
+	node := &filteredSpan{
+		id:          span.SpanContext().SpanID(),
+		startParent: span.Parent(),
+		droppable:   droppable,
+		debug:       debug,
+	}
+	if waits {
+		node.parent = parent
+		parent.children = append(parent.children, node)
+	}
+	p.spans[node.id] = node
+}
+
+// OnEnd implements trace.SpanProcessor.
+func (p *spanFilterProcessor) OnEnd(span trace.ReadOnlySpan) {
+	p.mu.Lock()
+	node, ok := p.spans[span.SpanContext().SpanID()]
+	if !ok {
+		p.mu.Unlock()
+		span, _ = p.apply(span, false)
+		p.SpanProcessor.OnEnd(span)
+		return
+	}
+	node.span = span
+	if !node.debug {
+		node.span, node.dropped = p.apply(span, node.droppable)
+	}
+
+	// Wait for the ancestor to end while the export queue has room
+	var ready []trace.ReadOnlySpan
+	if node.parent != nil && p.stats.spanQueueSize.Add(1) <= p.stats.spanQueueCapacity {
+		node.held = true
+	} else {
+		if node.parent != nil {
+			p.stats.spanQueueSize.Add(-1)
+			node.parent.children = slices.DeleteFunc(node.parent.children, func(child *filteredSpan) bool {
+				return child == node
+			})
+			node.parent = nil
+		}
+		ready = p.resolve(node, ready)
+	}
+	p.mu.Unlock()
+
+	for _, span := range ready {
+		p.SpanProcessor.OnEnd(span)
+	}
+}
+
+// resolve exports an ended span whose ancestors have been decided, unless it
+// is dropped, then settles its children, appending the spans to export to
+// ready.
+func (p *spanFilterProcessor) resolve(node *filteredSpan, ready []trace.ReadOnlySpan) []trace.ReadOnlySpan {
+	delete(p.spans, node.id)
+	if node.held {
+		node.held = false
+		p.stats.spanQueueSize.Add(-1)
+	}
+
+	// Children of a dropped span take its place under its parent
+	var childParent *oteltrace.SpanContext
+	if node.dropped {
+		parent := node.exportParent()
+		childParent = &parent
+	} else {
+		span := node.span
+		if node.reparent != nil {
+			span = &spanWithParent{ReadOnlySpan: span, parent: *node.reparent}
+		}
+		ready = append(ready, span)
+	}
+
+	children := node.children
+	node.children = nil
+	for _, child := range children {
+		ready = p.settle(child, childParent, ready)
+	}
+	return ready
+}
+
+// settle attaches a waiting span to parent, or leaves its parent as it is if
+// parent is nil, and resolves it if it has ended.
+func (p *spanFilterProcessor) settle(node *filteredSpan, parent *oteltrace.SpanContext, ready []trace.ReadOnlySpan) []trace.ReadOnlySpan {
+	node.parent = nil
+	node.reparent = parent
+	if node.span != nil {
+		return p.resolve(node, ready)
+	}
+	if !node.droppable && !node.debug && node.reparent == nil {
+		delete(p.spans, node.id)
+	}
+	return ready
+}
+
+// release stops holding spans for the spans that are still open, returning
+// the held spans to export. Flushing keeps open spans as parents, as they may
+// still be exported, while shutting down treats them as dropped, as they
+// never will be.
+func (p *spanFilterProcessor) release(shutdown bool) []trace.ReadOnlySpan {
+	var ready []trace.ReadOnlySpan
+	for {
+		var open []*filteredSpan
+		for _, node := range p.spans {
+			if node.parent == nil && node.span == nil && len(node.children) > 0 {
+				open = append(open, node)
+			}
+		}
+		if len(open) == 0 {
+			break
+		}
+
+		for _, node := range open {
+			var childParent *oteltrace.SpanContext
+			if shutdown {
+				parent := node.exportParent()
+				childParent = &parent
+			}
+			children := node.children
+			node.children = nil
+			for _, child := range children {
+				ready = p.settle(child, childParent, ready)
+			}
+		}
+	}
+	if shutdown {
+		clear(p.spans)
+	}
+	return ready
+}
+
+// ForceFlush implements trace.SpanProcessor. Spans waiting for an open
+// ancestor are exported with their parent as it is.
+func (p *spanFilterProcessor) ForceFlush(ctx context.Context) error {
+	p.mu.Lock()
+	ready := p.release(false)
+	p.mu.Unlock()
+
+	for _, span := range ready {
+		p.SpanProcessor.OnEnd(span)
+	}
+	return p.SpanProcessor.ForceFlush(ctx)
+}
+
+// Shutdown implements trace.SpanProcessor. Spans still open will not be
+// exported, so spans waiting for them are attached to the nearest ancestor
+// that may be.
+func (p *spanFilterProcessor) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	ready := p.release(true)
+	p.mu.Unlock()
+
+	for _, span := range ready {
+		p.SpanProcessor.OnEnd(span)
+	}
+	return p.SpanProcessor.Shutdown(ctx)
+}
+
+// withoutAttributes returns span without the attributes whose keys match
+// any of the patterns.
+func withoutAttributes(span trace.ReadOnlySpan, patterns []string) trace.ReadOnlySpan {
+	existing := span.Attributes()
+	kept := make([]attribute.KeyValue, 0, len(existing))
+	for _, attr := range existing {
+		drop := false
+		for _, pattern := range patterns {
+			if matchPattern(pattern, string(attr.Key)) {
+				drop = true
+				break
+			}
+		}
+		if !drop {
+			kept = append(kept, attr)
+		}
+	}
+	return &spanWithAttributes{ReadOnlySpan: span, attrs: kept}
+}
+
+// spanWithParent overrides the parent of a finished span.
+type spanWithParent struct {
+	trace.ReadOnlySpan
+	parent oteltrace.SpanContext
+}
+
+// Parent implements trace.ReadOnlySpan.
+func (s *spanWithParent) Parent() oteltrace.SpanContext {
+	return s.parent
+}
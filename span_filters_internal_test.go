@@ -0,0 +1,194 @@
+package opentelemetry
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// newFilteredTracer returns a tracer whose spans pass through a span filter
+// dropping util spans into a recorder, with room for capacity spans in the
+// export queue.
+func newFilteredTracer(t *testing.T, capacity int64) (oteltrace.Tracer, *spanFilterProcessor, *tracetest.SpanRecorder, *pipelineStats) {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	stats := newPipelineStats()
+	stats.spanQueueCapacity = capacity
+	filters, err := newSpanFilterProcessor(recorder, []SpanFilter{{Type: "util"}}, stats)
+	if err != nil {
+		t.Fatalf("newSpanFilterProcessor() error = %v", err)
+	}
+	provider := trace.NewTracerProvider(trace.WithSpanProcessor(filters))
+	return provider.Tracer("test"), filters, recorder, stats
+}
+
+// startUtil starts a span the filter drops.
+func startUtil(ctx context.Context, tracer oteltrace.Tracer, name string) (context.Context, oteltrace.Span) {
+	return tracer.Start(ctx, name, oteltrace.WithAttributes(attribute.String(genkitTypeAttr, "util")))
+}
+
+// endedParents returns the parent span IDs of the ended spans by name.
+func endedParents(recorder *tracetest.SpanRecorder) map[string]oteltrace.SpanID {
+	parents := map[string]oteltrace.SpanID{}
+	for _, span := range recorder.Ended() {
+		parents[span.Name()] = span.Parent().SpanID()
+	}
+	return parents
+}
+
+func TestSpanFilterHoldsChildrenUntilParentEnds(t *testing.T) {
+	tracer, _, recorder, stats := newFilteredTracer(t, 10)
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	ctx, outer := startUtil(ctx, tracer, "outer")
+	ctx, inner := startUtil(ctx, tracer, "inner")
+	_, child := tracer.Start(ctx, "child")
+	child.End()
+
+	if got := len(recorder.Ended()); got != 0 {
+		t.Fatalf("%d spans passed on before their parents ended, want the child held", got)
+	}
+	if got := stats.spanQueueSize.Load(); got != 1 {
+		t.Errorf("spanQueueSize = %d, want 1 for the held child", got)
+	}
+
+	inner.End()
+	if got := len(recorder.Ended()); got != 0 {
+		t.Fatalf("%d spans passed on before the dropped inner span's parent ended", got)
+	}
+	outer.End()
+	root.End()
+
+	parents := endedParents(recorder)
+	if _, ok := parents["outer"]; ok {
+		t.Error("outer span was passed on, want it dropped")
+	}
+	if _, ok := parents["inner"]; ok {
+		t.Error("inner span was passed on, want it dropped")
+	}
+	if got, want := parents["child"], root.SpanContext().SpanID(); got != want {
+		t.Errorf("child parent = %v, want root %v", got, want)
+	}
+	if got := stats.spanQueueSize.Load(); got != 0 {
+		t.Errorf("spanQueueSize = %d after release, want 0", got)
+	}
+}
+
+func TestSpanFilterPassesChildrenOfKeptSpans(t *testing.T) {
+	tracer, _, recorder, _ := newFilteredTracer(t, 10)
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := tracer.Start(ctx, "child")
+	child.End()
+	if got := len(recorder.Ended()); got != 1 {
+		t.Fatalf("%d spans passed on, want the child of a kept span passed on at once", got)
+	}
+	root.End()
+}
+
+func TestSpanFilterReparentsHeldSpansOnShutdown(t *testing.T) {
+	tracer, filters, recorder, _ := newFilteredTracer(t, 10)
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	ctx, _ = startUtil(ctx, tracer, "step")
+	_, child := tracer.Start(ctx, "child")
+	child.End()
+
+	if err := filters.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if got, want := endedParents(recorder)["child"], root.SpanContext().SpanID(); got != want {
+		t.Errorf("child parent = %v, want root %v, as the open step is never exported", got, want)
+	}
+	if got := len(filters.spans); got != 0 {
+		t.Errorf("%d spans still tracked after shutdown", got)
+	}
+}
+
+func TestSpanFilterDoesNotHoldWhenQueueIsFull(t *testing.T) {
+	tracer, _, recorder, stats := newFilteredTracer(t, 0)
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	ctx, step := startUtil(ctx, tracer, "step")
+	_, child := tracer.Start(ctx, "child")
+	child.End()
+
+	if got, want := endedParents(recorder)["child"], step.SpanContext().SpanID(); got != want {
+		t.Errorf("child parent = %v, want the step %v it was released with", got, want)
+	}
+	if got := stats.spanQueueSize.Load(); got != 0 {
+		t.Errorf("spanQueueSize = %d, want 0 with a full queue", got)
+	}
+	step.End()
+	root.End()
+}
+
+func TestSpanFilterOnlyHoldsChildrenOfCandidates(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	stats := newPipelineStats()
+	stats.spanQueueCapacity = 10
+	filters, err := newSpanFilterProcessor(recorder, []SpanFilter{
+		{GenkitName: "dropped"},
+		{Type: "util", Attributes: map[string]string{"team": "search"}},
+	}, stats)
+	if err != nil {
+		t.Fatalf("newSpanFilterProcessor() error = %v", err)
+	}
+	tracer := trace.NewTracerProvider(trace.WithSpanProcessor(filters)).Tracer("test")
+
+	// Neither the root nor a util span of another team can be dropped, so
+	// their children pass straight through
+	rootCtx, root := tracer.Start(context.Background(), "root")
+	ctx, other := tracer.Start(rootCtx, "other", oteltrace.WithAttributes(
+		attribute.String(genkitTypeAttr, "util"),
+		attribute.String("team", "billing"),
+	))
+	_, child := tracer.Start(ctx, "child")
+	child.End()
+	if got := len(recorder.Ended()); got != 1 {
+		t.Fatalf("%d spans passed on, want the child of spans no filter drops passed on at once", got)
+	}
+	if got := len(filters.spans); got != 0 {
+		t.Errorf("%d spans tracked, want none", got)
+	}
+	other.End()
+
+	// A span named after the dropped genkit name holds its children
+	ctx, dropped := tracer.Start(rootCtx, "dropped")
+	_, grandchild := tracer.Start(ctx, "grandchild")
+	grandchild.End()
+	dropped.SetAttributes(attribute.String(genkitNameAttr, "dropped"))
+	dropped.End()
+	root.End()
+
+	parents := endedParents(recorder)
+	if _, ok := parents["dropped"]; ok {
+		t.Error("dropped span was passed on")
+	}
+	if got, want := parents["grandchild"], root.SpanContext().SpanID(); got != want {
+		t.Errorf("grandchild parent = %v, want root %v", got, want)
+	}
+}
+
+func TestSpanFilterValidation(t *testing.T) {
+	tests := []struct {
+		filter  SpanFilter
+		wantErr bool
+	}{
+		{SpanFilter{}, true},
+		{SpanFilter{Type: "util"}, false},
+		{SpanFilter{GenkitName: "googleai/*"}, false},
+		{SpanFilter{Attributes: map[string]string{"team": "search"}}, true},
+		{SpanFilter{Attributes: map[string]string{"team": "search"}, DropAttributes: []string{"genkit:input"}}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.filter.validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%+v.validate() error = %v, want error %v", tt.filter, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package opentelemetry_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+	opentelemetry "github.com/xavidop/genkit-opentelemetry-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestSpanFilterReparentsChildrenOfDroppedSpans(t *testing.T) {
+	tel, g := newTestGenkit(t, opentelemetry.Config{SpanFilters: []opentelemetry.SpanFilter{{Type: "flowStep"}}})
+	flow := genkit.DefineFlow(g, "stepsFlow", func(ctx context.Context, input string) (string, error) {
+		return genkit.Run(ctx, "outer", func() (string, error) {
+			return genkit.Run(ctx, "inner", func() (string, error) {
+				resp, err := genkit.Generate(ctx, g, ai.WithModelName(testModel), ai.WithPrompt(input))
+				if err != nil {
+					return "", err
+				}
+				return resp.Text(), nil
+			})
+		})
+	})
+	runFlow(t, flow, "hi")
+
+	spans := tel.FindFlowTrace("stepsFlow")
+	for _, span := range spans {
+		if span.Name == "outer" || span.Name == "inner" {
+			t.Errorf("span %q was exported, want it dropped", span.Name)
+		}
+	}
+	root := spanByName(t, spans, "stepsFlow")
+	generate := spanByName(t, spans, "generate")
+	if got, want := parentID(generate), root.SpanContext.SpanID(); got != want {
+		t.Errorf("generate parent = %v, want the flow span %v", got, want)
+	}
+	model := spanByName(t, spans, testModel)
+	if got, want := parentID(model), generate.SpanContext.SpanID(); got != want {
+		t.Errorf("model parent = %v, want the generate span %v", got, want)
+	}
+}
+
+func TestSpanFilterDropsAttributes(t *testing.T) {
+	tel, g := newTestGenkit(t, opentelemetry.Config{SpanFilters: []opentelemetry.SpanFilter{{
+		Type:           "action",
+		GenkitName:     "test/*",
+		DropAttributes: []string{"genkit:input", "genkit:metadata:*"},
+	}}})
+	runFlow(t, defineGenerateFlow(g, "redactedFlow"), "hi")
+
+	model := tel.AssertSpan(testModel, attribute.String("genkit:name", testModel))
+	for _, key := range []string{"genkit:input", "genkit:metadata:subtype"} {
+		if hasAttribute(model, key) {
+			t.Errorf("model span has %s, want it removed", key)
+		}
+	}
+	if !hasAttribute(model, "genkit:output") {
+		t.Error("model span lost genkit:output, which no pattern matches")
+	}
+	flow := tel.AssertSpan("redactedFlow")
+	if !hasAttribute(flow, "genkit:input") {
+		t.Error("flow span lost genkit:input, but the filter only matches the model")
+	}
+	// Metrics see the spans before they are filtered
+	tel.AssertMetric("genkit/ai/generate/requests", 1, attribute.String("modelName", testModel))
+}
+
+func TestSpanFilterKeepsDebuggedFlows(t *testing.T) {
+	tel, g := newTestGenkit(t, opentelemetry.Config{SpanFilters: []opentelemetry.SpanFilter{{Type: "util"}}})
+	flow := defineGenerateFlow(g, "debugFilterFlow")
+	runFlow(t, flow, "hi")
+	if spans := tel.FindFlowTrace("debugFilterFlow"); hasSpan(spans, "generate") {
+		t.Fatal("generate span was exported, want it dropped")
+	}
+
+	tel.Reset()
+	tel.Plugin.RegisterFlows(g)
+	if _, err := tel.Plugin.EnableDebug("debugFilterFlow", 0); err != nil {
+		t.Fatalf("EnableDebug() error = %v", err)
+	}
+	runFlow(t, flow, "hi")
+	if spans := tel.FindFlowTrace("debugFilterFlow"); !hasSpan(spans, "generate") {
+		t.Error("generate span of a debugged flow was dropped")
+	}
+}
+
+func TestSpanFilterKeepsDebuggedFlowUnderSampledParent(t *testing.T) {
+	tel, g := newTestGenkit(t, opentelemetry.Config{SpanFilters: []opentelemetry.SpanFilter{{
+		Name:           "dflow",
+		DropAttributes: []string{"genkit:input"},
+	}}})
+	flow := defineGenerateFlow(g, "dflow")
+	tel.Plugin.RegisterFlows(g)
+	if _, err := tel.Plugin.EnableDebug("dflow", 0); err != nil {
+		t.Fatalf("EnableDebug() error = %v", err)
+	}
+
+	// A remote caller that already sampled the trace, as with FlowHandler
+	parent := oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    oteltrace.TraceID{1},
+		SpanID:     oteltrace.SpanID{1},
+		TraceFlags: oteltrace.FlagsSampled,
+		Remote:     true,
+	})
+	ctx := oteltrace.ContextWithRemoteSpanContext(context.Background(), parent)
+	if _, err := flow.Run(ctx, "hi"); err != nil {
+		t.Fatalf("flow.Run() error = %v", err)
+	}
+
+	root := tel.AssertSpan("dflow", attribute.Bool("genkit.debug", true))
+	if got := root.SpanContext.TraceID(); got != parent.TraceID() {
+		t.Errorf("flow trace ID = %v, want the remote parent's %v", got, parent.TraceID())
+	}
+	if !hasAttribute(root, "genkit:input") {
+		t.Error("debugged flow span lost genkit:input")
+	}
+}
+
+func TestSpanFilterReleasesHeldSpansOnFlush(t *testing.T) {
+	tel, _ := newTestGenkit(t, opentelemetry.Config{SpanFilters: []opentelemetry.SpanFilter{{Type: "util"}}})
+	tracer := otel.Tracer("test")
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	ctx, step := tracer.Start(ctx, "step", oteltrace.WithAttributes(attribute.String("genkit:type", "util")))
+	_, child := tracer.Start(ctx, "child")
+	child.End()
+
+	// The child waits for its parent, which may still be kept
+	exported := tel.AssertSpan("child")
+	if got, want := parentID(exported), step.SpanContext().SpanID(); got != want {
+		t.Errorf("child parent = %v, want the open step %v", got, want)
+	}
+
+	step.End()
+	root.End()
+	tel.AssertSpan("root")
+	if hasSpan(tel.Spans.GetSpans(), "step") {
+		t.Error("step was exported, want it dropped")
+	}
+}
@@ -0,0 +1,176 @@
+package opentelemetry
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	otelmetric "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Dimensions of the span metrics that are read from the span itself rather
+// than from its attributes.
+const (
+	SpanMetricsDimensionName   = "span.name"
+	SpanMetricsDimensionKind   = "span.kind"
+	SpanMetricsDimensionStatus = "status.code"
+)
+
+// defaultSpanMetricsDimensions are used when Config.SpanMetricsDimensions is empty.
+var defaultSpanMetricsDimensions = []string{
+	SpanMetricsDimensionName,
+	genkitTypeAttr,
+	genkitNameAttr,
+	SpanMetricsDimensionStatus,
+}
+
+// spanMetrics is a span processor that derives rate, error and duration
+// metrics from every finished span, like the collector's spanmetrics
+// connector. Errors are the calls with status.code STATUS_CODE_ERROR.
+type spanMetrics struct {
+	calls    otelmetric.Int64Counter
+	duration otelmetric.Float64Histogram
+
+	dimensions []string
+	exclude    []spanMatcher
+}
+
+// newSpanMetrics creates the span metric instruments on the given meter provider.
+func newSpanMetrics(mp otelmetric.MeterProvider, dimensions []string, buckets []float64, exclude []string) (*spanMetrics, error) {
+	if len(dimensions) == 0 {
+		dimensions = defaultSpanMetricsDimensions
+	}
+	if len(buckets) == 0 {
+		buckets = DefaultDurationBucketsMs
+	}
+
+	meter := mp.Meter(instrumentationName)
+	m := &spanMetrics{dimensions: dimensions}
+	for _, rule := range exclude {
+		m.exclude = append(m.exclude, newSpanMatcher(rule))
+	}
+
+	var err error
+	if m.calls, err = meter.Int64Counter("traces.span.metrics.calls",
+		otelmetric.WithDescription("Number of finished spans."),
+		otelmetric.WithUnit("{call}")); err != nil {
+		return nil, err
+	}
+	if m.duration, err = meter.Float64Histogram("traces.span.metrics.duration",
+		otelmetric.WithDescription("Duration of finished spans."),
+		otelmetric.WithUnit("ms"),
+		otelmetric.WithExplicitBucketBoundaries(buckets...)); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// OnStart implements trace.SpanProcessor.
+func (m *spanMetrics) OnStart(context.Context, trace.ReadWriteSpan) {}
+
+// OnEnd implements trace.SpanProcessor.
+func (m *spanMetrics) OnEnd(span trace.ReadOnlySpan) {
+	for _, matcher := range m.exclude {
+		if matcher.matches(span) {
+			return
+		}
+	}
+
+	ctx := spanContext(span)
+	opt := otelmetric.WithAttributes(m.attributes(span)...)
+	m.calls.Add(ctx, 1, opt)
+	m.duration.Record(ctx, spanLatencyMs(span), opt)
+}
+
+// Shutdown implements trace.SpanProcessor.
+func (m *spanMetrics) Shutdown(context.Context) error { return nil }
+
+// ForceFlush implements trace.SpanProcessor.
+func (m *spanMetrics) ForceFlush(context.Context) error { return nil }
+
+// attributes returns the configured dimensions of a span. Attributes missing
+// from the span are left out.
+func (m *spanMetrics) attributes(span trace.ReadOnlySpan) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(m.dimensions))
+	for _, dim := range m.dimensions {
+		switch dim {
+		case SpanMetricsDimensionName:
+			attrs = append(attrs, attribute.String(dim, span.Name()))
+		case SpanMetricsDimensionKind:
+			attrs = append(attrs, attribute.String(dim, "SPAN_KIND_"+strings.ToUpper(span.SpanKind().String())))
+		case SpanMetricsDimensionStatus:
+			attrs = append(attrs, attribute.String(dim, statusCodeName(span.Status().Code)))
+		default:
+			for _, attr := range span.Attributes() {
+				if string(attr.Key) == dim {
+					attrs = append(attrs, attr)
+					break
+				}
+			}
+		}
+	}
+	return attrs
+}
+
+// statusCodeName returns the OTLP name of a span status code.
+func statusCodeName(code codes.Code) string {
+	switch code {
+	case codes.Ok:
+		return "STATUS_CODE_OK"
+	case codes.Error:
+		return "STATUS_CODE_ERROR"
+	default:
+		return "STATUS_CODE_UNSET"
+	}
+}
+
+// spanMatcher matches spans by name, or by attribute value for rules of the
+// form "key=value". Names and values may contain "*" wildcards.
+type spanMatcher struct {
+	key     string // empty to match the span name
+	pattern string
+}
+
+// newSpanMatcher parses a matching rule.
+func newSpanMatcher(rule string) spanMatcher {
+	if key, pattern, found := strings.Cut(rule, "="); found {
+		return spanMatcher{key: key, pattern: pattern}
+	}
+	return spanMatcher{pattern: rule}
+}
+
+// matches reports whether the span matches the rule.
+func (s spanMatcher) matches(span trace.ReadOnlySpan) bool {
+	if s.key == "" {
+		return matchPattern(s.pattern, span.Name())
+	}
+	for _, attr := range span.Attributes() {
+		if string(attr.Key) == s.key {
+			return matchPattern(s.pattern, attr.Value.Emit())
+		}
+	}
+	return false
+}
+
+// matchPattern reports whether value matches pattern, where "*" matches any
+// sequence of characters, including "/".
+func matchPattern(pattern, value string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == value
+	}
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
+	}
+	value = value[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(value, part)
+		if i < 0 {
+			return false
+		}
+		value = value[i+len(part):]
+	}
+	return strings.HasSuffix(value, parts[len(parts)-1])
+}
@@ -0,0 +1,76 @@
+package opentelemetry_test
+
+import (
+	"testing"
+
+	opentelemetry "github.com/xavidop/genkit-opentelemetry-go"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestSpanMetrics(t *testing.T) {
+	tel, g := newTestGenkit(t, opentelemetry.Config{EnableSpanMetrics: true})
+	runFlow(t, defineGenerateFlow(g, "spanMetricsFlow"), "hi")
+
+	tel.AssertMetric("traces.span.metrics.calls", 1,
+		attribute.String("span.name", "spanMetricsFlow"),
+		attribute.String("genkit:type", "action"),
+		attribute.String("genkit:name", "spanMetricsFlow"),
+		attribute.String("status.code", "STATUS_CODE_UNSET"))
+	tel.AssertMetric("traces.span.metrics.duration", 1,
+		attribute.String("span.name", testModel),
+		attribute.String("genkit:name", testModel))
+}
+
+func TestSpanMetricsDimensions(t *testing.T) {
+	tel, g := newTestGenkit(t, opentelemetry.Config{
+		EnableSpanMetrics:     true,
+		SpanMetricsDimensions: []string{"span.kind", "genkit:metadata:subtype"},
+	})
+	runFlow(t, defineGenerateFlow(g, "dimensionsFlow"), "hi")
+
+	point := tel.AssertMetric("traces.span.metrics.calls", 1, attribute.String("genkit:metadata:subtype", "model"))
+	if got, want := point.Attributes.Len(), 2; got != want {
+		t.Errorf("data point has %d attributes, want %d: %v", got, want, point.Attributes.ToSlice())
+	}
+	if got, _ := point.Attributes.Value("span.kind"); got.AsString() != "SPAN_KIND_INTERNAL" {
+		t.Errorf("span.kind = %q, want SPAN_KIND_INTERNAL", got.AsString())
+	}
+}
+
+func TestSpanMetricsExclude(t *testing.T) {
+	tests := []struct {
+		name     string
+		exclude  []string
+		excluded []string // span names without metrics
+		included []string // span names with metrics
+	}{
+		{"exact name", []string{"generate"}, []string{"generate"}, []string{"excludeFlow", testModel}},
+		{"wildcard across slashes", []string{"test*"}, []string{testModel}, []string{"excludeFlow", "generate"}},
+		{"wildcard in the middle", []string{"ex*Flow"}, []string{"excludeFlow"}, []string{"generate", testModel}},
+		{"attribute", []string{"genkit:type=util"}, []string{"generate"}, []string{"excludeFlow", testModel}},
+		{"attribute wildcard", []string{"genkit:name=*/model"}, []string{testModel}, []string{"excludeFlow", "generate"}},
+		{"missing attribute", []string{"missing=*"}, nil, []string{"excludeFlow", "generate", testModel}},
+		{"no match", []string{"exclude"}, nil, []string{"excludeFlow", "generate", testModel}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tel, g := newTestGenkit(t, opentelemetry.Config{EnableSpanMetrics: true, SpanMetricsExclude: tt.exclude})
+			runFlow(t, defineGenerateFlow(g, "excludeFlow"), "hi")
+
+			for _, name := range tt.included {
+				tel.AssertMetric("traces.span.metrics.calls", 1, attribute.String("span.name", name))
+			}
+			for _, point := range tel.Metrics.Points() {
+				if point.Name != "traces.span.metrics.calls" {
+					continue
+				}
+				name, _ := point.Attributes.Value("span.name")
+				for _, excluded := range tt.excluded {
+					if name.AsString() == excluded {
+						t.Errorf("span %q has metrics, want it excluded by %v", excluded, tt.exclude)
+					}
+				}
+			}
+		})
+	}
+}
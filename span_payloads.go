@@ -0,0 +1,119 @@
+package opentelemetry
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/firebase/genkit/go/ai"
+	"go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// spanPayloadGeneration is the number of decoded payloads kept before the
+// oldest are evicted. Payloads are normally removed once their span has been
+// exported, so this only bounds spans that are dropped on the way.
+const spanPayloadGeneration = 2048
+
+// spanPayloadKey identifies a JSON attribute of a span.
+type spanPayloadKey struct {
+	traceID oteltrace.TraceID
+	spanID  oteltrace.SpanID
+	attr    string
+}
+
+// spanPayload is a decoded attribute and the JSON it was decoded from.
+type spanPayload struct {
+	raw   string
+	value any
+}
+
+// spanPayloads caches the decoded genkit:input and genkit:output attributes
+// of finished spans, shared by the span processors and export transforms so
+// each payload is decoded once. Entries are only used while the attribute
+// still holds the JSON they were decoded from, so spans whose attributes
+// were changed or dropped on the way are decoded again.
+type spanPayloads struct {
+	mu sync.Mutex
+
+	// Entries move to previous when current is full, and are evicted when
+	// current fills again
+	current  map[spanPayloadKey]spanPayload
+	previous map[spanPayloadKey]spanPayload
+}
+
+// newSpanPayloads creates an empty cache.
+func newSpanPayloads() *spanPayloads {
+	return &spanPayloads{current: map[spanPayloadKey]spanPayload{}}
+}
+
+// lookup returns the cached payload for key.
+func (p *spanPayloads) lookup(key spanPayloadKey) (spanPayload, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if payload, ok := p.current[key]; ok {
+		return payload, true
+	}
+	payload, ok := p.previous[key]
+	return payload, ok
+}
+
+// store caches a decoded payload.
+func (p *spanPayloads) store(key spanPayloadKey, payload spanPayload) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.current) >= spanPayloadGeneration {
+		p.previous = p.current
+		p.current = make(map[spanPayloadKey]spanPayload, spanPayloadGeneration)
+	}
+	p.current[key] = payload
+}
+
+// forget is a span transform removing the payloads of an exported span. It
+// runs after the transforms reading them.
+func (p *spanPayloads) forget(span trace.ReadOnlySpan) trace.ReadOnlySpan {
+	sc := span.SpanContext()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, attr := range []string{genkitInputAttr, genkitOutputAttr} {
+		key := spanPayloadKey{traceID: sc.TraceID(), spanID: sc.SpanID(), attr: attr}
+		delete(p.current, key)
+		delete(p.previous, key)
+	}
+	return span
+}
+
+// decodePayload decodes the JSON attribute attr of span into a T, using the
+// cache if payloads is not nil. The result is shared between callers and
+// must not be modified.
+func decodePayload[T any](payloads *spanPayloads, span trace.ReadOnlySpan, attr string) (T, bool) {
+	var value T
+	raw := stringAttribute(span.Attributes(), attr)
+	if raw == "" {
+		return value, false
+	}
+
+	var key spanPayloadKey
+	if payloads != nil {
+		sc := span.SpanContext()
+		key = spanPayloadKey{traceID: sc.TraceID(), spanID: sc.SpanID(), attr: attr}
+		if cached, ok := payloads.lookup(key); ok && cached.raw == raw {
+			if value, ok := cached.value.(T); ok {
+				return value, true
+			}
+		}
+	}
+
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return value, false
+	}
+	if payloads != nil {
+		payloads.store(key, spanPayload{raw: raw, value: value})
+	}
+	return value, true
+}
+
+// modelResponse returns the model response recorded on a model span.
+func modelResponse(payloads *spanPayloads, span trace.ReadOnlySpan) ai.ModelResponse {
+	output, _ := decodePayload[ai.ModelResponse](payloads, span, genkitOutputAttr)
+	return output
+}
@@ -0,0 +1,185 @@
+package opentelemetry
+
+import (
+	"context"
+	"time"
+
+	"github.com/firebase/genkit/go/ai"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// defaultStreamChunkEvents is the default chunk event sampling interval.
+const defaultStreamChunkEvents = 10
+
+// streamStallThreshold is the gap between chunks after which a chunk event is
+// always recorded, regardless of sampling.
+const streamStallThreshold = time.Second
+
+// streamingMetrics holds the instruments recorded for streaming model calls.
+type streamingMetrics struct {
+	timeToFirstToken  otelmetric.Float64Histogram
+	interChunkLatency otelmetric.Float64Histogram
+	tokensPerSecond   otelmetric.Float64Histogram
+
+	// Record a chunk event every eventEvery chunks, never if <= 0
+	eventEvery int
+}
+
+// StreamingMiddleware returns model middleware that records time-to-first-token,
+// inter-chunk latency and output tokens-per-second for streaming model calls,
+// and adds sampled chunk events to the model span. Calls without a streaming
+// callback are passed through untouched.
+//
+// Genkit has no hook for installing model middleware globally, so streaming
+// calls are only observed when the middleware is passed to them:
+//
+//	genkit.Generate(ctx, g, ai.WithMiddleware(otelPlugin.StreamingMiddleware()), ai.WithStreaming(cb), ...)
+//
+// Middleware runs outside the model span and only sees it through the
+// context the model passes to its stream callback. For models that pass
+// another context, chunk events are added to the enclosing span and metrics
+// are recorded with modelName "<unknown>".
+func (ot *OpenTelemetry) StreamingMiddleware() ai.ModelMiddleware {
+	var mp otelmetric.MeterProvider = otel.GetMeterProvider()
+	if ot.meterProvider != nil {
+		mp = ot.meterProvider
+	}
+	m, err := newStreamingMetrics(mp, ot.config.StreamChunkEvents)
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	return func(next ai.ModelFunc) ai.ModelFunc {
+		return func(ctx context.Context, req *ai.ModelRequest, cb ai.ModelStreamCallback) (*ai.ModelResponse, error) {
+			if cb == nil || m == nil {
+				return next(ctx, req, cb)
+			}
+			s := &streamObserver{metrics: m, start: time.Now(), span: oteltrace.SpanFromContext(ctx)}
+			resp, err := next(ctx, req, s.wrap(cb))
+			s.finish(ctx, resp, err)
+			return resp, err
+		}
+	}
+}
+
+// newStreamingMetrics creates the streaming instruments on the given meter provider.
+func newStreamingMetrics(mp otelmetric.MeterProvider, eventEvery int) (*streamingMetrics, error) {
+	meter := mp.Meter(genkitMeterName)
+	m := &streamingMetrics{eventEvery: eventEvery}
+
+	var err error
+	if m.timeToFirstToken, err = meter.Float64Histogram("genkit/ai/generate/time_to_first_token",
+		otelmetric.WithDescription("Time from the start of a streaming model call to its first chunk."),
+		otelmetric.WithUnit("ms"),
+		otelmetric.WithExplicitBucketBoundaries(DefaultDurationBucketsMs...)); err != nil {
+		return nil, err
+	}
+	if m.interChunkLatency, err = meter.Float64Histogram("genkit/ai/generate/inter_chunk_latency",
+		otelmetric.WithDescription("Time between consecutive chunks of a streaming model call."),
+		otelmetric.WithUnit("ms"),
+		otelmetric.WithExplicitBucketBoundaries(DefaultChunkBucketsMs...)); err != nil {
+		return nil, err
+	}
+	if m.tokensPerSecond, err = meter.Float64Histogram("genkit/ai/generate/output/tokens_per_second",
+		otelmetric.WithDescription("Output tokens per second of a streaming model call between its first and last chunk."),
+		otelmetric.WithUnit("{token}/s"),
+		otelmetric.WithExplicitBucketBoundaries(DefaultThroughputBuckets...)); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// streamObserver tracks the chunks of a single streaming model call.
+type streamObserver struct {
+	metrics *streamingMetrics
+	start   time.Time
+
+	// The model span, taken from the context of the first chunk, or the
+	// enclosing span if the model does not pass its context
+	span      oteltrace.Span
+	modelName string
+
+	chunks     int
+	firstChunk time.Time
+	lastChunk  time.Time
+}
+
+// wrap returns a callback that observes each chunk before passing it to cb.
+func (s *streamObserver) wrap(cb ai.ModelStreamCallback) ai.ModelStreamCallback {
+	return func(ctx context.Context, chunk *ai.ModelResponseChunk) error {
+		s.observe(ctx)
+		return cb(ctx, chunk)
+	}
+}
+
+// observe records the metrics and span event for a chunk.
+func (s *streamObserver) observe(ctx context.Context) {
+	now := time.Now()
+	s.chunks++
+
+	var gap time.Duration
+	if s.chunks == 1 {
+		s.firstChunk = now
+		if span := oteltrace.SpanFromContext(ctx); span.SpanContext().IsValid() {
+			s.span = span
+		}
+		s.modelName = spanModelName(s.span)
+		s.metrics.timeToFirstToken.Record(ctx, durationMs(now.Sub(s.start)), s.attributes())
+	} else {
+		gap = now.Sub(s.lastChunk)
+		s.metrics.interChunkLatency.Record(ctx, durationMs(gap), s.attributes())
+	}
+	s.lastChunk = now
+
+	sampled := s.metrics.eventEvery > 0 && (s.chunks == 1 || s.chunks%s.metrics.eventEvery == 0)
+	if sampled || gap >= streamStallThreshold {
+		s.span.AddEvent("genkit.stream.chunk", oteltrace.WithAttributes(
+			attribute.Int("genkit.stream.chunk.index", s.chunks),
+			attribute.Float64("genkit.stream.elapsed_ms", durationMs(now.Sub(s.start))),
+			attribute.Float64("genkit.stream.gap_ms", durationMs(gap)),
+		))
+	}
+}
+
+// finish records the output throughput once the model call has returned. It
+// is measured over the streamed interval, from the first to the last chunk,
+// so neither the wait for the first token nor the work after the last chunk
+// counts. Calls streaming a single chunk have no interval and are skipped.
+func (s *streamObserver) finish(ctx context.Context, resp *ai.ModelResponse, err error) {
+	if err != nil || s.chunks < 2 || resp == nil || resp.Usage == nil || resp.Usage.OutputTokens == 0 {
+		return
+	}
+	elapsed := s.lastChunk.Sub(s.firstChunk).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	s.metrics.tokensPerSecond.Record(ctx, float64(resp.Usage.OutputTokens)/elapsed, s.attributes())
+}
+
+// attributes returns the metric attributes for the call.
+func (s *streamObserver) attributes() otelmetric.MeasurementOption {
+	return otelmetric.WithAttributes(
+		attribute.String("modelName", truncate(s.modelName, 1024)),
+		attribute.String("source", "go"),
+		attribute.String("sourceVersion", genkitSourceVersion()),
+	)
+}
+
+// spanModelName returns the model name of a Genkit model span. genkit:name is
+// only set when the span ends, so the span name is used instead.
+func spanModelName(span oteltrace.Span) string {
+	ro, ok := span.(trace.ReadOnlySpan)
+	if !ok || stringAttribute(ro.Attributes(), genkitTypeAttr) != "action" {
+		return "<unknown>"
+	}
+	return ro.Name()
+}
+
+// durationMs converts d to fractional milliseconds.
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,97 @@
+package opentelemetry_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+	opentelemetry "github.com/xavidop/genkit-opentelemetry-go"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// streamingModel is the name of the model defined by defineStreamingModel.
+const streamingModel = "test/streaming"
+
+// defineStreamingModel defines a model that streams chunks chunks a few
+// milliseconds apart and reports 100 output tokens.
+func defineStreamingModel(g *genkit.Genkit, chunks int) {
+	genkit.DefineModel(g, streamingModel, &ai.ModelOptions{Supports: &ai.ModelSupports{Multiturn: true}},
+		func(ctx context.Context, req *ai.ModelRequest, cb ai.ModelStreamCallback) (*ai.ModelResponse, error) {
+			if cb != nil {
+				for range chunks {
+					time.Sleep(2 * time.Millisecond)
+					if err := cb(ctx, &ai.ModelResponseChunk{Content: []*ai.Part{ai.NewTextPart("tok ")}}); err != nil {
+						return nil, err
+					}
+				}
+			}
+			return &ai.ModelResponse{
+				Message:      ai.NewModelTextMessage("done"),
+				FinishReason: ai.FinishReasonStop,
+				Usage:        &ai.GenerationUsage{OutputTokens: 100},
+			}, nil
+		})
+}
+
+func TestStreamingMiddleware(t *testing.T) {
+	tel, g := newTestGenkit(t, opentelemetry.Config{StreamChunkEvents: 2})
+	defineStreamingModel(g, 5)
+	middleware := tel.Plugin.StreamingMiddleware()
+
+	received := 0
+	_, err := genkit.Generate(context.Background(), g,
+		ai.WithModelName(streamingModel),
+		ai.WithPrompt("hi"),
+		ai.WithMiddleware(middleware),
+		ai.WithStreaming(func(ctx context.Context, chunk *ai.ModelResponseChunk) error {
+			received++
+			return nil
+		}))
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if received != 5 {
+		t.Errorf("callback received %d chunks, want 5", received)
+	}
+
+	model := attribute.String("modelName", streamingModel)
+	tel.AssertMetric("genkit/ai/generate/time_to_first_token", 1, model)
+	tel.AssertMetric("genkit/ai/generate/inter_chunk_latency", 4, model)
+	throughput := tel.AssertMetric("genkit/ai/generate/output/tokens_per_second", 1, model)
+	if throughput.Value <= 0 {
+		t.Errorf("tokens_per_second = %v, want a positive rate", throughput.Value)
+	}
+
+	// The first chunk and every second one are recorded on the model span
+	span := tel.AssertSpan(streamingModel)
+	var indexes []int64
+	for _, event := range span.Events {
+		if event.Name != "genkit.stream.chunk" {
+			continue
+		}
+		for _, attr := range event.Attributes {
+			if attr.Key == "genkit.stream.chunk.index" {
+				indexes = append(indexes, attr.Value.AsInt64())
+			}
+		}
+	}
+	if !slices.Equal(indexes, []int64{1, 2, 4}) {
+		t.Errorf("chunk events at %v, want [1 2 4]", indexes)
+	}
+
+	// Calls without a stream callback are not observed
+	tel.Reset()
+	if _, err := genkit.Generate(context.Background(), g,
+		ai.WithModelName(streamingModel), ai.WithPrompt("hi"), ai.WithMiddleware(middleware)); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	tel.AssertSpan(streamingModel)
+	for _, point := range tel.Metrics.Points() {
+		if point.Name == "genkit/ai/generate/time_to_first_token" {
+			t.Errorf("time_to_first_token recorded %d times for a call without streaming", point.Count)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package opentelemetry
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// MetricTemporality selects the aggregation temporality of exported metrics.
+type MetricTemporality string
+
+const (
+	// TemporalityCumulative reports totals since the process started (default)
+	TemporalityCumulative MetricTemporality = "cumulative"
+
+	// TemporalityDelta reports changes since the last export for counters
+	// and histograms, as required by backends such as Datadog and Dynatrace
+	TemporalityDelta MetricTemporality = "delta"
+
+	// TemporalityLowMemory reports synchronous counters and histograms as
+	// delta and everything else as cumulative
+	TemporalityLowMemory MetricTemporality = "lowmemory"
+)
+
+// temporalityEnv is the standard environment variable selecting the temporality.
+const temporalityEnv = "OTEL_EXPORTER_OTLP_METRICS_TEMPORALITY_PREFERENCE"
+
+// selector returns the temporality selector for t.
+func (t MetricTemporality) selector() (metric.TemporalitySelector, error) {
+	switch MetricTemporality(strings.ToLower(string(t))) {
+	case TemporalityCumulative:
+		return metric.DefaultTemporalitySelector, nil
+	case TemporalityDelta:
+		return deltaTemporality, nil
+	case TemporalityLowMemory:
+		return lowMemoryTemporality, nil
+	default:
+		return nil, fmt.Errorf("unknown metric temporality %q", t)
+	}
+}
+
+// deltaTemporality uses delta for monotonic instruments and cumulative for
+// up-down counters and gauges, which cannot be summed across exports.
+func deltaTemporality(kind metric.InstrumentKind) metricdata.Temporality {
+	switch kind {
+	case metric.InstrumentKindCounter,
+		metric.InstrumentKindHistogram,
+		metric.InstrumentKindObservableCounter:
+		return metricdata.DeltaTemporality
+	default:
+		return metricdata.CumulativeTemporality
+	}
+}
+
+// lowMemoryTemporality uses delta only for synchronous counters and histograms.
+func lowMemoryTemporality(kind metric.InstrumentKind) metricdata.Temporality {
+	switch kind {
+	case metric.InstrumentKindCounter,
+		metric.InstrumentKindHistogram:
+		return metricdata.DeltaTemporality
+	default:
+		return metricdata.CumulativeTemporality
+	}
+}
+
+// metricTemporality returns the temporality for the plugin's default
+// exporters: Config.MetricTemporality, then the standard environment
+// variable. It returns "" when neither is set so exporters keep their default.
+func (ot *OpenTelemetry) metricTemporality() MetricTemporality {
+	if ot.config.MetricTemporality != "" {
+		return ot.config.MetricTemporality
+	}
+
+	// Like the OTLP exporters, ignore invalid environment values
+	env := MetricTemporality(os.Getenv(temporalityEnv))
+	if env == "" {
+		return ""
+	}
+	if _, err := env.selector(); err != nil {
+		slog.Warn("Ignoring invalid metric temporality", "env", temporalityEnv, "error", err)
+		return ""
+	}
+	return env
+}
+
+// temporalityExporter overrides the temporality of a user provided exporter.
+type temporalityExporter struct {
+	metric.Exporter
+	selector metric.TemporalitySelector
+}
+
+// Temporality implements metric.Exporter.
+func (e *temporalityExporter) Temporality(kind metric.InstrumentKind) metricdata.Temporality {
+	return e.selector(kind)
+}
@@ -0,0 +1,88 @@
+package opentelemetry
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestMetricTemporalitySelector(t *testing.T) {
+	const (
+		cumulative = metricdata.CumulativeTemporality
+		delta      = metricdata.DeltaTemporality
+	)
+	kinds := []metric.InstrumentKind{
+		metric.InstrumentKindCounter,
+		metric.InstrumentKindHistogram,
+		metric.InstrumentKindObservableCounter,
+		metric.InstrumentKindUpDownCounter,
+		metric.InstrumentKindObservableUpDownCounter,
+		metric.InstrumentKindGauge,
+		metric.InstrumentKindObservableGauge,
+	}
+	tests := []struct {
+		temporality MetricTemporality
+		want        []metricdata.Temporality // by kind
+	}{
+		{TemporalityCumulative, []metricdata.Temporality{cumulative, cumulative, cumulative, cumulative, cumulative, cumulative, cumulative}},
+		{TemporalityDelta, []metricdata.Temporality{delta, delta, delta, cumulative, cumulative, cumulative, cumulative}},
+		{TemporalityLowMemory, []metricdata.Temporality{delta, delta, cumulative, cumulative, cumulative, cumulative, cumulative}},
+		{"Delta", []metricdata.Temporality{delta, delta, delta, cumulative, cumulative, cumulative, cumulative}},
+		{"LOWMEMORY", []metricdata.Temporality{delta, delta, cumulative, cumulative, cumulative, cumulative, cumulative}},
+	}
+	for _, tt := range tests {
+		selector, err := tt.temporality.selector()
+		if err != nil {
+			t.Errorf("%q.selector() error = %v", tt.temporality, err)
+			continue
+		}
+		for i, kind := range kinds {
+			if got := selector(kind); got != tt.want[i] {
+				t.Errorf("%q.selector()(%v) = %v, want %v", tt.temporality, kind, got, tt.want[i])
+			}
+		}
+	}
+
+	if _, err := MetricTemporality("sometimes").selector(); err == nil {
+		t.Error(`"sometimes".selector() succeeded, want an error`)
+	}
+}
+
+func TestMetricTemporalityPrecedence(t *testing.T) {
+	tests := []struct {
+		name   string
+		config MetricTemporality
+		env    string
+		want   MetricTemporality
+	}{
+		{"unset", "", "", ""},
+		{"environment", "", "delta", "delta"},
+		{"environment in upper case", "", "LowMemory", "LowMemory"},
+		{"invalid environment is ignored", "", "sometimes", ""},
+		{"config over environment", TemporalityCumulative, "delta", TemporalityCumulative},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(temporalityEnv, tt.env)
+			ot := New(Config{MetricTemporality: tt.config})
+			if got := ot.metricTemporality(); got != tt.want {
+				t.Errorf("metricTemporality() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemporalityExporter(t *testing.T) {
+	selector, err := TemporalityDelta.selector()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exporter := &temporalityExporter{selector: selector}
+	if got := exporter.Temporality(metric.InstrumentKindCounter); got != metricdata.DeltaTemporality {
+		t.Errorf("Temporality(counter) = %v, want delta", got)
+	}
+	if got := exporter.Temporality(metric.InstrumentKindUpDownCounter); got != metricdata.CumulativeTemporality {
+		t.Errorf("Temporality(up-down counter) = %v, want cumulative", got)
+	}
+}
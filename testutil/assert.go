@@ -0,0 +1,124 @@
+package testutil
+
+import (
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// AssertSpan waits for a span with the given name and attributes and returns
+// it. The span may carry other attributes too. The test fails if no such span
+// is exported before the timeout.
+func (tel *Telemetry) AssertSpan(name string, attrs ...attribute.KeyValue) tracetest.SpanStub {
+	tel.t.Helper()
+
+	var match tracetest.SpanStub
+	found := tel.waitFor(func() bool {
+		for _, span := range tel.Spans.GetSpans() {
+			if span.Name == name && hasAttributes(span.Attributes, attrs) {
+				match = span
+				return true
+			}
+		}
+		return false
+	})
+	if !found {
+		tel.t.Fatalf("no span %q with attributes %v, exported spans: %s", name, attrs, spanNames(tel.Spans.GetSpans()))
+	}
+	return match
+}
+
+// AssertMetric waits for a data point of the named metric with the given
+// attributes and value. For histograms, value is the number of recorded
+// measurements. The data point may carry other attributes too. The test fails
+// if no such data point is exported before the timeout.
+func (tel *Telemetry) AssertMetric(name string, value float64, attrs ...attribute.KeyValue) MetricPoint {
+	tel.t.Helper()
+
+	var match MetricPoint
+	var seen []MetricPoint
+	found := tel.waitFor(func() bool {
+		seen = seen[:0]
+		for _, point := range tel.Metrics.Points() {
+			if point.Name != name || !hasAttributes(point.Attributes.ToSlice(), attrs) {
+				continue
+			}
+			seen = append(seen, point)
+			got := point.Value
+			if point.Histogram {
+				got = float64(point.Count)
+			}
+			if got == value {
+				match = point
+				return true
+			}
+		}
+		return false
+	})
+	if !found {
+		if len(seen) == 0 {
+			tel.t.Fatalf("no data point of metric %q with attributes %v", name, attrs)
+		}
+		tel.t.Fatalf("no data point of metric %q with attributes %v and value %v, got %v", name, attrs, value, seen)
+	}
+	return match
+}
+
+// FindFlowTrace waits for the root span of the named flow to be exported and
+// returns all spans of its trace, ordered by start time. The test fails if
+// the flow does not finish before the timeout.
+func (tel *Telemetry) FindFlowTrace(flowName string) tracetest.SpanStubs {
+	tel.t.Helper()
+
+	var traceID oteltrace.TraceID
+	found := tel.waitFor(func() bool {
+		for _, span := range tel.Spans.GetSpans() {
+			if span.Name == flowName && hasAttributes(span.Attributes, []attribute.KeyValue{
+				attribute.String("genkit:metadata:subtype", "flow"),
+				attribute.Bool("genkit:isRoot", true),
+			}) {
+				traceID = span.SpanContext.TraceID()
+				return true
+			}
+		}
+		return false
+	})
+	if !found {
+		tel.t.Fatalf("no trace of flow %q, exported spans: %s", flowName, spanNames(tel.Spans.GetSpans()))
+	}
+
+	var spans tracetest.SpanStubs
+	for _, span := range tel.Spans.GetSpans() {
+		if span.SpanContext.TraceID() == traceID {
+			spans = append(spans, span)
+		}
+	}
+	slices.SortStableFunc(spans, func(a, b tracetest.SpanStub) int {
+		return a.StartTime.Compare(b.StartTime)
+	})
+	return spans
+}
+
+// hasAttributes reports whether attrs contains every attribute in want.
+func hasAttributes(attrs, want []attribute.KeyValue) bool {
+	for _, w := range want {
+		if !slices.ContainsFunc(attrs, func(a attribute.KeyValue) bool {
+			return a.Key == w.Key && a.Value == w.Value
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// spanNames lists the names of spans for failure messages.
+func spanNames(spans tracetest.SpanStubs) string {
+	names := make([]string, len(spans))
+	for i, span := range spans {
+		names[i] = span.Name
+	}
+	return "[" + strings.Join(names, ", ") + "]"
+}
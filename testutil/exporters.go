@@ -0,0 +1,256 @@
+package testutil
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// MetricPoint is a single exported data point.
+type MetricPoint struct {
+	// Instrument name.
+	Name string
+
+	// Data point attributes.
+	Attributes attribute.Set
+
+	// Value of a sum or gauge, or the sum of a histogram.
+	Value float64
+
+	// Number of measurements recorded in a histogram. Zero for other kinds.
+	Count uint64
+
+	// Whether the point belongs to a histogram.
+	Histogram bool
+
+	// Whether Value and Count are changes since the previous export, which
+	// the exporter adds up.
+	delta bool
+}
+
+// key identifies the time series of the point.
+func (p MetricPoint) key() metricKey {
+	return metricKey{name: p.Name, attrs: p.Attributes.Equivalent()}
+}
+
+// metricKey identifies a time series.
+type metricKey struct {
+	name  string
+	attrs attribute.Distinct
+}
+
+// MetricExporter is a metric exporter keeping the running totals of every
+// exported time series in memory. Cumulative points replace the previous
+// value, while delta points are added to it.
+type MetricExporter struct {
+	mu     sync.Mutex
+	points []MetricPoint
+}
+
+// NewMetricExporter creates an empty in-memory metric exporter.
+func NewMetricExporter() *MetricExporter {
+	return &MetricExporter{}
+}
+
+// Points returns the running totals of the exported time series.
+func (e *MetricExporter) Points() []MetricPoint {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return slices.Clone(e.points)
+}
+
+// Reset discards the exported data points.
+func (e *MetricExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.points = nil
+}
+
+// Temporality implements metric.Exporter.
+func (e *MetricExporter) Temporality(kind metric.InstrumentKind) metricdata.Temporality {
+	return metric.DefaultTemporalitySelector(kind)
+}
+
+// Aggregation implements metric.Exporter.
+func (e *MetricExporter) Aggregation(kind metric.InstrumentKind) metric.Aggregation {
+	return metric.DefaultAggregationSelector(kind)
+}
+
+// Export implements metric.Exporter. The SDK reuses rm after Export returns,
+// so the data points are copied out.
+func (e *MetricExporter) Export(_ context.Context, rm *metricdata.ResourceMetrics) error {
+	var points []MetricPoint
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			points = appendPoints(points, m)
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.points = mergePoints(e.points, points)
+	return nil
+}
+
+// mergePoints returns the points of an export combined with the totals of
+// the previous exports. Delta series without new measurements are left out
+// of an export, so their previous totals are kept.
+func mergePoints(previous, exported []MetricPoint) []MetricPoint {
+	totals := make(map[metricKey]MetricPoint, len(previous))
+	for _, point := range previous {
+		totals[point.key()] = point
+	}
+
+	merged := make([]MetricPoint, 0, len(exported))
+	seen := make(map[metricKey]bool, len(exported))
+	for _, point := range exported {
+		key := point.key()
+		if total, ok := totals[key]; ok && point.delta {
+			point.Value += total.Value
+			point.Count += total.Count
+		}
+		merged = append(merged, point)
+		seen[key] = true
+	}
+	for _, point := range previous {
+		if point.delta && !seen[point.key()] {
+			merged = append(merged, point)
+		}
+	}
+	return merged
+}
+
+// ForceFlush implements metric.Exporter.
+func (e *MetricExporter) ForceFlush(context.Context) error { return nil }
+
+// Shutdown implements metric.Exporter.
+func (e *MetricExporter) Shutdown(context.Context) error { return nil }
+
+// appendPoints appends the data points of m to points.
+func appendPoints(points []MetricPoint, m metricdata.Metrics) []MetricPoint {
+	switch data := m.Data.(type) {
+	case metricdata.Sum[int64]:
+		for _, dp := range data.DataPoints {
+			points = append(points, MetricPoint{Name: m.Name, Attributes: dp.Attributes, Value: float64(dp.Value), delta: isDelta(data.Temporality)})
+		}
+	case metricdata.Sum[float64]:
+		for _, dp := range data.DataPoints {
+			points = append(points, MetricPoint{Name: m.Name, Attributes: dp.Attributes, Value: dp.Value, delta: isDelta(data.Temporality)})
+		}
+	case metricdata.Gauge[int64]:
+		for _, dp := range data.DataPoints {
+			points = append(points, MetricPoint{Name: m.Name, Attributes: dp.Attributes, Value: float64(dp.Value)})
+		}
+	case metricdata.Gauge[float64]:
+		for _, dp := range data.DataPoints {
+			points = append(points, MetricPoint{Name: m.Name, Attributes: dp.Attributes, Value: dp.Value})
+		}
+	case metricdata.Histogram[int64]:
+		for _, dp := range data.DataPoints {
+			points = append(points, MetricPoint{Name: m.Name, Attributes: dp.Attributes, Value: float64(dp.Sum), Count: dp.Count, Histogram: true, delta: isDelta(data.Temporality)})
+		}
+	case metricdata.Histogram[float64]:
+		for _, dp := range data.DataPoints {
+			points = append(points, MetricPoint{Name: m.Name, Attributes: dp.Attributes, Value: dp.Sum, Count: dp.Count, Histogram: true, delta: isDelta(data.Temporality)})
+		}
+	case metricdata.ExponentialHistogram[int64]:
+		for _, dp := range data.DataPoints {
+			points = append(points, MetricPoint{Name: m.Name, Attributes: dp.Attributes, Value: float64(dp.Sum), Count: dp.Count, Histogram: true, delta: isDelta(data.Temporality)})
+		}
+	case metricdata.ExponentialHistogram[float64]:
+		for _, dp := range data.DataPoints {
+			points = append(points, MetricPoint{Name: m.Name, Attributes: dp.Attributes, Value: dp.Sum, Count: dp.Count, Histogram: true, delta: isDelta(data.Temporality)})
+		}
+	}
+	return points
+}
+
+// isDelta reports whether points of temporality t are changes since the
+// previous export.
+func isDelta(t metricdata.Temporality) bool {
+	return t == metricdata.DeltaTemporality
+}
+
+// LogRecorder keeps the log records written through its handler in memory.
+type LogRecorder struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+// NewLogRecorder creates an empty log recorder.
+func NewLogRecorder() *LogRecorder {
+	return &LogRecorder{}
+}
+
+// Handler returns a slog.Handler recording every log record.
+func (r *LogRecorder) Handler() slog.Handler {
+	return &logHandler{recorder: r}
+}
+
+// Records returns the recorded log records.
+func (r *LogRecorder) Records() []slog.Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.records)
+}
+
+// Reset discards the recorded log records.
+func (r *LogRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = nil
+}
+
+// logHandler is the slog.Handler of a LogRecorder. Attributes in groups are
+// recorded with the group names as a dotted key prefix.
+type logHandler struct {
+	recorder *LogRecorder
+	attrs    []slog.Attr
+	prefix   string
+}
+
+// Enabled implements slog.Handler.
+func (h *logHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+// Handle implements slog.Handler.
+func (h *logHandler) Handle(_ context.Context, record slog.Record) error {
+	flat := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	flat.AddAttrs(h.attrs...)
+	record.Attrs(func(attr slog.Attr) bool {
+		flat.AddAttrs(h.prefixed(attr))
+		return true
+	})
+
+	h.recorder.mu.Lock()
+	defer h.recorder.mu.Unlock()
+	h.recorder.records = append(h.recorder.records, flat)
+	return nil
+}
+
+// WithAttrs implements slog.Handler.
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	all := slices.Clone(h.attrs)
+	for _, attr := range attrs {
+		all = append(all, h.prefixed(attr))
+	}
+	return &logHandler{recorder: h.recorder, attrs: all, prefix: h.prefix}
+}
+
+// WithGroup implements slog.Handler.
+func (h *logHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &logHandler{recorder: h.recorder, attrs: h.attrs, prefix: h.prefix + name + "."}
+}
+
+// prefixed returns attr with the open groups prepended to its key.
+func (h *logHandler) prefixed(attr slog.Attr) slog.Attr {
+	attr.Key = h.prefix + attr.Key
+	return attr
+}
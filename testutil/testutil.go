@@ -0,0 +1,140 @@
+// Package testutil wires in-memory exporters into the OpenTelemetry plugin so
+// tests can assert on the spans, metrics and logs their Genkit flows emit.
+//
+//	func TestGreetingFlow(t *testing.T) {
+//		tel := testutil.NewForTest(t)
+//		g := genkit.Init(ctx, genkit.WithPlugins(tel.Plugin))
+//		flow := genkit.DefineFlow(g, "greeting", greet)
+//		flow.Run(ctx, "world")
+//
+//		tel.AssertSpan("greeting", attribute.String("genkit:state", "success"))
+//		tel.AssertMetric("genkit/feature/requests", 1, attribute.String("name", "greeting"))
+//	}
+package testutil
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	opentelemetry "github.com/xavidop/genkit-opentelemetry-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// DefaultTimeout is how long assertions wait for spans and metrics that are
+// still being recorded, e.g. by flows running in other goroutines.
+const DefaultTimeout = 5 * time.Second
+
+// pollInterval is the delay between flushes while waiting for telemetry.
+const pollInterval = 10 * time.Millisecond
+
+// Telemetry is an OpenTelemetry plugin exporting to memory.
+type Telemetry struct {
+	// Plugin to pass to genkit.Init.
+	Plugin *opentelemetry.OpenTelemetry
+
+	// Exported spans.
+	Spans *tracetest.InMemoryExporter
+
+	// Exported metrics.
+	Metrics *MetricExporter
+
+	// Recorded log records.
+	Logs *LogRecorder
+
+	// How long assertions wait for telemetry. Defaults to DefaultTimeout.
+	Timeout time.Duration
+
+	t testing.TB
+}
+
+// NewForTest creates a plugin exporting spans, metrics and logs to memory. The
+// optional config is applied on top of the test defaults; its exporters, log
+// handler and metric temporality are replaced. Pass Telemetry.Plugin to
+// genkit.Init.
+//
+// The plugin installs global providers, so tests using it must not run in
+// parallel. The previous globals are restored when the test ends.
+func NewForTest(t testing.TB, customConfig ...opentelemetry.Config) *Telemetry {
+	t.Helper()
+
+	var config opentelemetry.Config
+	if len(customConfig) > 0 {
+		config = customConfig[0]
+	}
+
+	tel := &Telemetry{
+		Spans:   tracetest.NewInMemoryExporter(),
+		Metrics: NewMetricExporter(),
+		Logs:    NewLogRecorder(),
+		Timeout: DefaultTimeout,
+		t:       t,
+	}
+
+	config.ForceExport = true
+	config.TraceExporter = tel.Spans
+	config.MetricExporter = tel.Metrics
+	config.LogHandler = tel.Logs.Handler()
+	config.EnablePrometheusExporter = false
+	config.EnablePrometheusEndpoint = false
+	// The exporter adds deltas up to running totals, so counts start from zero
+	// again after Reset
+	config.MetricTemporality = opentelemetry.TemporalityDelta
+	// Metrics are only exported on flush
+	config.MetricInterval = time.Hour
+	tel.Plugin = opentelemetry.New(config)
+
+	tracerProvider := otel.GetTracerProvider()
+	meterProvider := otel.GetMeterProvider()
+	propagator := otel.GetTextMapPropagator()
+	logger := slog.Default()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+		defer cancel()
+		_ = tel.Plugin.Shutdown(ctx)
+
+		otel.SetTracerProvider(tracerProvider)
+		otel.SetMeterProvider(meterProvider)
+		otel.SetTextMapPropagator(propagator)
+		slog.SetDefault(logger)
+	})
+
+	return tel
+}
+
+// Flush exports all spans and metrics recorded so far.
+func (tel *Telemetry) Flush() {
+	tel.t.Helper()
+	if err := tel.Plugin.ForceFlush(context.Background()); err != nil {
+		tel.t.Fatalf("failed to flush telemetry: %v", err)
+	}
+}
+
+// Reset discards all exported spans, metrics and logs. Counters and
+// histograms count from zero again, while up-down counters and gauges keep
+// reporting their current value.
+func (tel *Telemetry) Reset() {
+	tel.Flush()
+	tel.Spans.Reset()
+	tel.Metrics.Reset()
+	tel.Logs.Reset()
+}
+
+// waitFor flushes until found reports true or the timeout expires, and
+// returns whether it did.
+func (tel *Telemetry) waitFor(found func() bool) bool {
+	tel.t.Helper()
+	deadline := time.Now().Add(tel.Timeout)
+	for {
+		tel.Flush()
+		if found() {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(pollInterval)
+	}
+}
@@ -0,0 +1,245 @@
+package testutil
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/firebase/genkit/go/genkit"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// sumMetrics returns resource metrics holding a single int64 sum data point.
+func sumMetrics(name string, value int64, temporality metricdata.Temporality, attrs ...attribute.KeyValue) *metricdata.ResourceMetrics {
+	return &metricdata.ResourceMetrics{ScopeMetrics: []metricdata.ScopeMetrics{{
+		Metrics: []metricdata.Metrics{{
+			Name: name,
+			Data: metricdata.Sum[int64]{
+				Temporality: temporality,
+				IsMonotonic: true,
+				DataPoints:  []metricdata.DataPoint[int64]{{Attributes: attribute.NewSet(attrs...), Value: value}},
+			},
+		}},
+	}}}
+}
+
+// pointValue returns the value of the named time series, or false if it was
+// not exported.
+func pointValue(e *MetricExporter, name string, attrs ...attribute.KeyValue) (float64, bool) {
+	set := attribute.NewSet(attrs...)
+	for _, point := range e.Points() {
+		if point.Name == name && point.Attributes.Equals(&set) {
+			return point.Value, true
+		}
+	}
+	return 0, false
+}
+
+func TestMetricExporterMergesExports(t *testing.T) {
+	tests := []struct {
+		name        string
+		temporality metricdata.Temporality
+		want        float64
+	}{
+		{"cumulative points replace the total", metricdata.CumulativeTemporality, 5},
+		{"delta points are added to the total", metricdata.DeltaTemporality, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewMetricExporter()
+			ctx := context.Background()
+			for _, value := range []int64{3, 5} {
+				if err := e.Export(ctx, sumMetrics("requests", value, tt.temporality)); err != nil {
+					t.Fatalf("Export() error = %v", err)
+				}
+			}
+			if got, ok := pointValue(e, "requests"); !ok || got != tt.want {
+				t.Errorf("requests = %v, %v, want %v", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetricExporterKeepsIdleDeltaSeries(t *testing.T) {
+	e := NewMetricExporter()
+	ctx := context.Background()
+	a := attribute.String("flow", "a")
+	b := attribute.String("flow", "b")
+
+	if err := e.Export(ctx, sumMetrics("requests", 2, metricdata.DeltaTemporality, a)); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	// The series of flow a has no new measurements, so it is left out
+	if err := e.Export(ctx, sumMetrics("requests", 1, metricdata.DeltaTemporality, b)); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if got, ok := pointValue(e, "requests", a); !ok || got != 2 {
+		t.Errorf("requests{flow=a} = %v, %v, want 2", got, ok)
+	}
+	if got, ok := pointValue(e, "requests", b); !ok || got != 1 {
+		t.Errorf("requests{flow=b} = %v, %v, want 1", got, ok)
+	}
+
+	e.Reset()
+	if got := len(e.Points()); got != 0 {
+		t.Errorf("Points() has %d points after Reset, want 0", got)
+	}
+}
+
+func TestMergePointsAddsHistogramCounts(t *testing.T) {
+	previous := []MetricPoint{{Name: "latency", Value: 10, Count: 2, Histogram: true, delta: true}}
+	exported := []MetricPoint{{Name: "latency", Value: 5, Count: 1, Histogram: true, delta: true}}
+
+	merged := mergePoints(previous, exported)
+	if len(merged) != 1 {
+		t.Fatalf("mergePoints() = %v, want a single point", merged)
+	}
+	if got := merged[0]; got.Value != 15 || got.Count != 3 {
+		t.Errorf("mergePoints() = value %v, count %d, want 15 and 3", got.Value, got.Count)
+	}
+}
+
+func TestLogRecorderPrefixesGroups(t *testing.T) {
+	recorder := NewLogRecorder()
+	logger := slog.New(recorder.Handler()).
+		With("service", "api").
+		WithGroup("request").
+		With("id", "42").
+		WithGroup("user")
+	logger.Info("handled", "name", "ada")
+
+	records := recorder.Records()
+	if len(records) != 1 {
+		t.Fatalf("Records() has %d records, want 1", len(records))
+	}
+	got := map[string]string{}
+	records[0].Attrs(func(attr slog.Attr) bool {
+		got[attr.Key] = attr.Value.String()
+		return true
+	})
+	want := map[string]string{"service": "api", "request.id": "42", "request.user.name": "ada"}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("attribute %q = %q, want %q (got %v)", key, got[key], value, got)
+		}
+	}
+
+	recorder.Reset()
+	if got := len(recorder.Records()); got != 0 {
+		t.Errorf("Records() has %d records after Reset, want 0", got)
+	}
+}
+
+func TestNewForTest(t *testing.T) {
+	tel := NewForTest(t)
+	g := genkit.Init(context.Background(), genkit.WithPlugins(tel.Plugin))
+	flow := genkit.DefineFlow(g, "echoFlow", func(ctx context.Context, input string) (string, error) {
+		return genkit.Run(ctx, "echo", func() (string, error) { return input, nil })
+	})
+
+	for range 2 {
+		if _, err := flow.Run(context.Background(), "hi"); err != nil {
+			t.Fatalf("flow.Run() error = %v", err)
+		}
+	}
+
+	tel.AssertSpan("echoFlow", attribute.String("genkit:state", "success"))
+	tel.AssertMetric("genkit/feature/requests", 2,
+		attribute.String("name", "echoFlow"),
+		attribute.String("status", "success"))
+	if point := tel.AssertMetric("genkit/feature/latency", 2, attribute.String("name", "echoFlow")); !point.Histogram {
+		t.Error("genkit/feature/latency is not a histogram")
+	}
+
+	spans := tel.FindFlowTrace("echoFlow")
+	if len(spans) != 2 || spans[0].Name != "echoFlow" || spans[1].Name != "echo" {
+		t.Errorf("FindFlowTrace() = %s, want [echoFlow, echo]", spanNames(spans))
+	}
+	if spans[1].Parent.SpanID() != spans[0].SpanContext.SpanID() {
+		t.Error("FindFlowTrace() returned spans of different traces")
+	}
+
+	tel.Reset()
+	if got := len(tel.Spans.GetSpans()); got != 0 {
+		t.Errorf("%d spans after Reset, want 0", got)
+	}
+	if got := len(tel.Metrics.Points()); got != 0 {
+		t.Errorf("%d metric points after Reset, want 0", got)
+	}
+}
+
+func TestResetStartsCountsFromZero(t *testing.T) {
+	tel := NewForTest(t)
+	g := genkit.Init(context.Background(), genkit.WithPlugins(tel.Plugin))
+	flow := genkit.DefineFlow(g, "countedFlow", func(ctx context.Context, input string) (string, error) {
+		return input, nil
+	})
+	name := attribute.String("name", "countedFlow")
+
+	for range 2 {
+		if _, err := flow.Run(context.Background(), "hi"); err != nil {
+			t.Fatalf("flow.Run() error = %v", err)
+		}
+	}
+	tel.AssertMetric("genkit/feature/requests", 2, name)
+
+	tel.Reset()
+	if _, err := flow.Run(context.Background(), "hi"); err != nil {
+		t.Fatalf("flow.Run() error = %v", err)
+	}
+	tel.AssertMetric("genkit/feature/requests", 1, name)
+	tel.AssertMetric("genkit/feature/latency", 1, name)
+}
+
+func TestAssertionsFailAfterTimeout(t *testing.T) {
+	tel := NewForTest(t)
+	genkit.Init(context.Background(), genkit.WithPlugins(tel.Plugin))
+
+	ft := &fatalRecorder{TB: t}
+	tel.t = ft
+	tel.Timeout = 50 * time.Millisecond
+
+	start := time.Now()
+	ft.run(func() { tel.AssertSpan("missing") })
+	if !ft.failed {
+		t.Error("AssertSpan() of a missing span did not fail the test")
+	}
+	if elapsed := time.Since(start); elapsed < tel.Timeout {
+		t.Errorf("AssertSpan() failed after %v, want it to wait %v", elapsed, tel.Timeout)
+	}
+
+	ft.failed = false
+	ft.run(func() { tel.AssertMetric("missing", 1) })
+	if !ft.failed {
+		t.Error("AssertMetric() of a missing metric did not fail the test")
+	}
+}
+
+// fatalRecorder records Fatalf calls instead of ending the test.
+type fatalRecorder struct {
+	testing.TB
+	failed bool
+}
+
+// errFatal unwinds an assertion that called Fatalf.
+type errFatal struct{}
+
+// Fatalf implements testing.TB.
+func (r *fatalRecorder) Fatalf(string, ...any) {
+	r.failed = true
+	panic(errFatal{})
+}
+
+// run calls assert, stopping at the first Fatalf.
+func (r *fatalRecorder) run(assert func()) {
+	defer func() {
+		if v := recover(); v != nil {
+			if _, ok := v.(errFatal); !ok {
+				panic(v)
+			}
+		}
+	}()
+	assert()
+}
@@ -0,0 +1,40 @@
+package opentelemetry
+
+import (
+	"slices"
+
+	"go.opentelemetry.io/otel/sdk/metric"
+)
+
+// defaultCardinalityLimit is the maximum number of distinct attribute sets
+// recorded per instrument before data is collapsed into the overflow series.
+const defaultCardinalityLimit = 2000
+
+// newMeterProvider creates the plugin's meter provider for the given reader,
+// applying the configured views, histogram aggregation, cardinality limit and
+// exemplar filter.
+func (ot *OpenTelemetry) newMeterProvider(reader metric.Reader) (*metric.MeterProvider, error) {
+	opts := []metric.Option{
+		metric.WithResource(ot.resource),
+		metric.WithReader(reader),
+	}
+
+	views := append(slices.Clone(ot.config.MetricViews), ot.histogramView())
+	opts = append(opts, metric.WithView(views...))
+
+	// Attribute sets past the limit are recorded under otel.metric.overflow=true
+	if ot.config.MetricCardinalityLimit > 0 {
+		opts = append(opts, metric.WithCardinalityLimit(ot.config.MetricCardinalityLimit))
+	}
+
+	// Without an explicit filter the SDK follows OTEL_METRICS_EXEMPLAR_FILTER
+	if ot.config.ExemplarFilter != "" {
+		filter, err := ot.config.ExemplarFilter.filter()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, metric.WithExemplarFilter(filter))
+	}
+
+	return metric.NewMeterProvider(opts...), nil
+}
@@ -0,0 +1,104 @@
+package opentelemetry
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// newManualMeterProvider returns the plugin's meter provider for config and a
+// reader collecting from it.
+func newManualMeterProvider(t *testing.T, config Config) (*metric.MeterProvider, *metric.ManualReader) {
+	t.Helper()
+	reader := metric.NewManualReader()
+	provider, err := New(config).newMeterProvider(reader)
+	if err != nil {
+		t.Fatalf("newMeterProvider() error = %v", err)
+	}
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	return provider, reader
+}
+
+// collectMetric collects the named metric and fails the test if it was not
+// recorded.
+func collectMetric(t *testing.T, reader *metric.ManualReader, name string) metricdata.Metrics {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m
+			}
+		}
+	}
+	t.Fatalf("metric %q was not collected", name)
+	return metricdata.Metrics{}
+}
+
+func TestMetricViews(t *testing.T) {
+	provider, reader := newManualMeterProvider(t, Config{MetricViews: []metric.View{
+		metric.NewView(metric.Instrument{Name: "requests"}, metric.Stream{
+			Name:            "renamed.requests",
+			AttributeFilter: attribute.NewAllowKeysFilter("flow"),
+		}),
+	}})
+	counter, err := provider.Meter("test").Int64Counter("requests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	counter.Add(context.Background(), 1, otelmetric.WithAttributes(
+		attribute.String("flow", "chat"),
+		attribute.String("user", "ada"),
+	))
+
+	sum, ok := collectMetric(t, reader, "renamed.requests").Data.(metricdata.Sum[int64])
+	if !ok || len(sum.DataPoints) != 1 {
+		t.Fatalf("renamed.requests = %+v, want a single sum data point", sum)
+	}
+	want := attribute.NewSet(attribute.String("flow", "chat"))
+	if got := sum.DataPoints[0].Attributes; !got.Equals(&want) {
+		t.Errorf("attributes = %v, want only flow", got.ToSlice())
+	}
+}
+
+func TestMetricCardinalityLimit(t *testing.T) {
+	tests := []struct {
+		limit      int
+		wantPoints int
+		overflow   bool
+	}{
+		{2, 2, true},
+		{0, 5, false}, // the default limit
+	}
+	for _, tt := range tests {
+		provider, reader := newManualMeterProvider(t, Config{MetricCardinalityLimit: tt.limit})
+		counter, err := provider.Meter("test").Int64Counter("requests")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, user := range []string{"a", "b", "c", "d", "e"} {
+			counter.Add(context.Background(), 1, otelmetric.WithAttributes(attribute.String("user", user)))
+		}
+
+		sum := collectMetric(t, reader, "requests").Data.(metricdata.Sum[int64])
+		if got := len(sum.DataPoints); got != tt.wantPoints {
+			t.Errorf("limit %d: %d data points, want %d", tt.limit, got, tt.wantPoints)
+		}
+		overflow := false
+		for _, dp := range sum.DataPoints {
+			if v, ok := dp.Attributes.Value("otel.metric.overflow"); ok && v.AsBool() {
+				overflow = true
+			}
+		}
+		if overflow != tt.overflow {
+			t.Errorf("limit %d: overflow series = %v, want %v", tt.limit, overflow, tt.overflow)
+		}
+	}
+}